@@ -0,0 +1,16 @@
+package examplemigrations
+
+import (
+	"embed"
+	"github.com/imthatgin/sas/pkg/migration"
+)
+
+//go:embed sql/*.sql
+var sqlMigrations embed.FS
+
+func init() {
+	err := migration.RegisterFS(sqlMigrations, "sql")
+	if err != nil {
+		panic(err)
+	}
+}
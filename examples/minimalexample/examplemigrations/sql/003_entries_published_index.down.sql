@@ -0,0 +1 @@
+DROP INDEX idx_entries_published;
@@ -0,0 +1 @@
+CREATE INDEX idx_entries_published ON entries (published);
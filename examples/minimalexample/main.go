@@ -4,12 +4,15 @@ import (
 	_ "github.com/imthatgin/sas/examples/minimalexample/examplemigrations"
 	"github.com/imthatgin/sas/pkg/endpoints"
 	"github.com/imthatgin/sas/pkg/migration"
+	"github.com/imthatgin/sas/pkg/openapi"
 	"github.com/imthatgin/sas/pkg/sas"
+	"github.com/imthatgin/sas/pkg/tsgen"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"os"
 )
 
 const (
@@ -24,7 +27,7 @@ type Entry struct {
 }
 
 func init() {
-	migration.MigrationsTableName = "__example_migrations_meta"
+	migration.Default.SetTableName("__example_migrations_meta")
 }
 
 func main() {
@@ -47,7 +50,51 @@ func main() {
 	}
 
 	models := addModels(db)
-	_ = sas.New(e, db, models)
+	migration.Default.TrackModels(sas.Models(models)...)
+
+	// "minimalexample migrate <command>" manages the migrations, and "minimalexample tsgen -out DIR" generates
+	// a TypeScript client, instead of starting the server.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			err := migration.CLI(db, os.Args[1:])
+			if err != nil {
+				log.Fatal("Migration command failed: ", err)
+			}
+			return
+		case "tsgen":
+			err := tsgen.Command(models, os.Args[1:], os.Stdout)
+			if err != nil {
+				log.Fatal("TypeScript generation failed: ", err)
+			}
+			return
+		}
+	}
+
+	s, err := sas.New(e, db, models,
+		sas.WithMigrations(migration.Default, true),
+		sas.WithPageSize(50, 500),
+		sas.WithAuditor(sas.NewAuditor()),
+	)
+	if err != nil {
+		log.Fatal("Server could not be created: ", err)
+	}
+
+	info := openapi.Info{
+		Title:   "Minimal example",
+		Version: "1.0.0",
+	}
+	s.ServeOpenAPI("/openapi.json", info)
+	if err := s.ServeExplorer("/_sas/explorer", info); err != nil {
+		log.Fatal("Explorer could not be served: ", err)
+	}
+	// Anyone may read the reports of the example, which has no authentication.
+	s.ServeResources("/_sas/resources", func(c sas.Context) bool {
+		return true
+	})
+	s.ServeAudit("/_sas/audit", func(c sas.Context) bool {
+		return true
+	})
 
 	log.Fatal(e.Start(":8082"))
 }
@@ -56,13 +103,13 @@ func addModels(db *gorm.DB) []sas.Provider {
 
 	entryPolicy := sas.NewPolicy[Entry](endpoints.AllEndpoints)
 	entryPolicy.
-		CanListAll(func(c echo.Context) bool {
+		CanListAll(func(c sas.Context) bool {
 			return true
 		}).
-		CanListById(func(c echo.Context, entity Entry) bool {
+		CanListById(func(c sas.Context, entity Entry) bool {
 			return entity.ID%2 == 0
 		}).
-		CanWriteById(func(c echo.Context, entity Entry) bool {
+		CanWriteById(func(c sas.Context, entity Entry) bool {
 			return true
 		})
 
@@ -71,6 +118,9 @@ func addModels(db *gorm.DB) []sas.Provider {
 	entries.WriteBindType(struct {
 		Content string
 	}{})
+	entries.Docs(sas.ResourceDocs{
+		Description: "Short text entries, of which only the even ones can be read.",
+	})
 
 	return []sas.Provider{
 		&entries,
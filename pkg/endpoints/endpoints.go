@@ -9,10 +9,39 @@ const (
 	GET HttpEndpointType = 1 << iota
 	PUT
 	POST
+	// PATCH only enables the bulk PATCH endpoint together with BULK. Single entities are written with PUT.
 	PATCH // TODO: Distinguish between patch and put
 	DELETE
+
+	// BULK enables the bulk variants of the other enabled endpoints: POST, PATCH and DELETE.
+	// It is not part of AllEndpoints.
+	BULK
 )
 
 func Has(flags HttpEndpointType, test HttpEndpointType) bool {
 	return flags&test == test
 }
+
+var names = []struct {
+	endpoint HttpEndpointType
+	name     string
+}{
+	{GET, "GET"},
+	{PUT, "PUT"},
+	{POST, "POST"},
+	{PATCH, "PATCH"},
+	{DELETE, "DELETE"},
+	{BULK, "BULK"},
+}
+
+// Names returns the HTTP methods set in flags.
+func Names(flags HttpEndpointType) []string {
+	var result []string
+	for _, n := range names {
+		if Has(flags, n.endpoint) {
+			result = append(result, n.name)
+		}
+	}
+
+	return result
+}
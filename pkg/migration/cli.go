@@ -0,0 +1,179 @@
+package migration
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrorUnknownCommand = errors.New("unknown migration command")
+
+const cliUsage = `Usage: %s <command> [arguments]
+
+Commands:
+  up                 apply all pending migrations
+  down [N]           revert the last N applied migrations (default 1)
+  to NAME            migrate up or down to the named migration
+  status             list every migration and whether it has been applied
+  plan               list the migrations up would apply
+  redo               revert and re-apply the last applied migration
+  baseline [NAME]    mark migrations up to NAME (default all) as applied without running them
+  create [-sql] [-dir DIR] [-package PKG] NAME
+                     scaffold a new numbered migration file
+  generate [-sql] [-dir DIR] [-package PKG] [-snapshot FILE] NAME
+                     create a migration for the differences between the tracked models and the schema
+`
+
+// CLI runs a migration command on the Default migrator. See Migrator.CLI.
+func CLI(db *gorm.DB, args []string) error {
+	return Default.CLI(db, args)
+}
+
+// CLI runs the migration command given by args, writing any output to stdout.
+// The first argument is the program or command name, so os.Args can be passed as is.
+func (m *Migrator) CLI(db *gorm.DB, args []string) error {
+	return m.command(db, args, os.Stdout)
+}
+
+func (m *Migrator) command(db *gorm.DB, args []string, out io.Writer) error {
+	program := "migrate"
+	if len(args) > 0 {
+		program = filepath.Base(args[0])
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		_, _ = fmt.Fprintf(out, cliUsage, program)
+		return nil
+	}
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "up":
+		return m.Run(db)
+
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			var err error
+			steps, err = strconv.Atoi(args[0])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("down expects a positive number of steps, got %q", args[0])
+			}
+		}
+		return m.Down(db, steps)
+
+	case "to":
+		if len(args) != 1 {
+			return errors.New("to expects a migration name")
+		}
+		return m.To(db, args[0])
+
+	case "status":
+		statuses, err := m.Status(db)
+		if err != nil {
+			return err
+		}
+		return writeStatus(out, statuses)
+
+	case "plan":
+		pending, err := m.Plan(db)
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			_, _ = fmt.Fprintln(out, "No pending migrations")
+		}
+		for _, migration := range pending {
+			_, _ = fmt.Fprintln(out, migration.Name)
+		}
+		return nil
+
+	case "redo":
+		return m.Redo(db)
+
+	case "baseline":
+		target := ""
+		if len(args) > 0 {
+			target = args[0]
+		}
+		return m.Baseline(db, target)
+
+	case "create", "generate":
+		flags := flag.NewFlagSet(command, flag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		sql := flags.Bool("sql", false, "create a pair of SQL files instead of a Go file")
+		dir := flags.String("dir", ".", "directory to create the migration in")
+		pkg := flags.String("package", "", "package name of the Go migration, defaults to the directory name")
+		snapshot := flags.String("snapshot", "", "schema snapshot to compare the models against, instead of the database")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return fmt.Errorf("%s expects a migration name", command)
+		}
+
+		options := GenerateOptions{
+			Name:     flags.Arg(0),
+			Dir:      *dir,
+			Package:  *pkg,
+			SQL:      *sql,
+			Snapshot: *snapshot,
+		}
+
+		var path string
+		var err error
+		if command == "create" {
+			path, err = m.Create(options)
+		} else {
+			path, err = m.Generate(db, options)
+		}
+		if err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintln(out, "Created", path)
+		return nil
+
+	case "help", "-h", "--help":
+		_, _ = fmt.Fprintf(out, cliUsage, program)
+		return nil
+
+	default:
+		return fmt.Errorf("%w: %s", ErrorUnknownCommand, command)
+	}
+}
+
+func writeStatus(out io.Writer, statuses []Status) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tSTATUS\tAPPLIED AT")
+
+	for _, status := range statuses {
+		state := "pending"
+		switch {
+		case status.Missing:
+			state = "applied (not registered)"
+		case status.Changed:
+			state = "applied (checksum changed)"
+		case status.Applied:
+			state = "applied"
+		}
+
+		appliedAt := ""
+		if status.Applied {
+			appliedAt = status.AppliedAt.UTC().Format(time.RFC3339)
+		}
+
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", status.Name, state, appliedAt)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,105 @@
+package migration
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newCLITestMigrator(t *testing.T) (*Migrator, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	assert.NoError(t, err)
+
+	migrator := NewMigrator()
+	for _, name := range []string{"001_one", "002_two", "003_three"} {
+		table := "table_" + name
+		migrator.Add(Migration{
+			Name:     name,
+			Checksum: name,
+			Up: func(db *gorm.DB) error {
+				return db.Exec("CREATE TABLE " + table + " (id INTEGER)").Error
+			},
+			Down: func(db *gorm.DB) error {
+				return db.Exec("DROP TABLE " + table).Error
+			},
+		})
+	}
+
+	return migrator, db
+}
+
+func runCLI(t *testing.T, migrator *Migrator, db *gorm.DB, args ...string) string {
+	var out bytes.Buffer
+	assert.NoError(t, migrator.command(db, append([]string{"app"}, args...), &out))
+	return out.String()
+}
+
+func TestCLI_UpDown(t *testing.T) {
+	migrator, db := newCLITestMigrator(t)
+
+	assert.Equal(t, "001_one\n002_two\n003_three\n", runCLI(t, migrator, db, "plan"))
+
+	runCLI(t, migrator, db, "up")
+	assert.True(t, db.Migrator().HasTable("table_003_three"))
+	assert.Equal(t, "No pending migrations\n", runCLI(t, migrator, db, "plan"))
+
+	runCLI(t, migrator, db, "down", "2")
+	assert.True(t, db.Migrator().HasTable("table_001_one"))
+	assert.False(t, db.Migrator().HasTable("table_002_two"))
+	assert.Equal(t, "002_two\n003_three\n", runCLI(t, migrator, db, "plan"))
+
+	runCLI(t, migrator, db, "to", "002_two")
+	assert.True(t, db.Migrator().HasTable("table_002_two"))
+	assert.False(t, db.Migrator().HasTable("table_003_three"))
+
+	runCLI(t, migrator, db, "to", "001_one")
+	assert.False(t, db.Migrator().HasTable("table_002_two"))
+
+	runCLI(t, migrator, db, "up")
+	runCLI(t, migrator, db, "redo")
+	assert.True(t, db.Migrator().HasTable("table_003_three"))
+
+	status := runCLI(t, migrator, db, "status")
+	assert.Contains(t, status, "003_three")
+	assert.NotContains(t, status, "pending")
+
+	var out bytes.Buffer
+	assert.ErrorIs(t, migrator.command(db, []string{"app", "to", "999_missing"}, &out), ErrorUnknownMigration)
+	assert.ErrorIs(t, migrator.command(db, []string{"app", "sideways"}, &out), ErrorUnknownCommand)
+}
+
+func TestCLI_Baseline(t *testing.T) {
+	migrator, db := newCLITestMigrator(t)
+
+	runCLI(t, migrator, db, "baseline", "002_two")
+	assert.False(t, db.Migrator().HasTable("table_001_one"))
+	assert.Equal(t, "003_three\n", runCLI(t, migrator, db, "plan"))
+
+	runCLI(t, migrator, db, "up")
+	assert.True(t, db.Migrator().HasTable("table_003_three"))
+	assert.False(t, db.Migrator().HasTable("table_002_two"))
+}
+
+func TestCLI_Create(t *testing.T) {
+	migrator, db := newCLITestMigrator(t)
+	dir := filepath.Join(t.TempDir(), "mymigrations")
+	assert.NoError(t, os.Mkdir(dir, 0o755))
+
+	out := runCLI(t, migrator, db, "create", "-dir", dir, "Add Users")
+	goPath := filepath.Join(dir, "004_add_users.go")
+	assert.Equal(t, "Created "+goPath+"\n", out)
+
+	content, err := os.ReadFile(goPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "package mymigrations")
+	assert.Contains(t, string(content), "migration.Register(")
+
+	runCLI(t, migrator, db, "create", "-sql", "-dir", dir, "add_index")
+	assert.FileExists(t, filepath.Join(dir, "005_add_index.up.sql"))
+	assert.FileExists(t, filepath.Join(dir, "005_add_index.down.sql"))
+}
@@ -0,0 +1,420 @@
+package migration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var ErrorNoSchemaChanges = errors.New("the schema is up to date with the models")
+
+// TableSnapshot is the schema of a table, as described by a model or read from a database.
+// Indexes is nil for snapshots written before indexes were recorded, whose indexes are not compared.
+type TableSnapshot struct {
+	Name    string           `json:"name"`
+	Columns []ColumnSnapshot `json:"columns"`
+	Indexes []IndexSnapshot  `json:"indexes"`
+}
+
+// ColumnSnapshot is the schema of a single column.
+// Type is the full column definition, such as "text NOT NULL".
+type ColumnSnapshot struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	PrimaryKey bool   `json:"primaryKey,omitempty"`
+}
+
+// IndexSnapshot is an index of a table, including the unique constraints of columns.
+// Name is empty for unique constraints read from a database which does not name them.
+type IndexSnapshot struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique,omitempty"`
+}
+
+// matches reports whether index is the same index as other, by name or by its columns.
+func (index IndexSnapshot) matches(other IndexSnapshot) bool {
+	if index.Name != "" && index.Name == other.Name {
+		return true
+	}
+
+	return index.Unique == other.Unique && slices.Equal(index.Columns, other.Columns)
+}
+
+// SchemaChanges holds the statements needed to move from one schema to another.
+// Changes which would lose data, such as removed columns, are only reported as warnings.
+type SchemaChanges struct {
+	Up   []string
+	Down []string
+
+	Warnings []string
+}
+
+// TrackModels adds models which Generate compares against the schema.
+// Models are pointers to structs, as accepted by GORM.
+func (m *Migrator) TrackModels(models ...any) {
+	m.lock()
+	defer m.mu.Unlock()
+
+	for _, model := range models {
+		modelType := reflect.TypeOf(model)
+
+		tracked := false
+		for _, existing := range m.models {
+			if reflect.TypeOf(existing) == modelType {
+				tracked = true
+				break
+			}
+		}
+
+		if !tracked {
+			m.models = append(m.models, model)
+		}
+	}
+}
+
+// Generate writes a migration containing the changes between the tracked models and the schema.
+// The schema is read from db, or from options.Snapshot when that file exists. The snapshot is updated afterwards.
+// The warnings of the changes are logged, and written as comments in the migration.
+// ErrorNoSchemaChanges is returned when there is nothing to migrate.
+func (m *Migrator) Generate(db *gorm.DB, options GenerateOptions) (string, error) {
+	m.lock()
+	models := m.models
+	m.mu.Unlock()
+
+	desired, err := ModelSchema(db, models...)
+	if err != nil {
+		return "", err
+	}
+
+	var current []TableSnapshot
+	fromSnapshot := false
+	if options.Snapshot != "" {
+		current, err = LoadSnapshot(options.Snapshot)
+		fromSnapshot = err == nil
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+
+	if !fromSnapshot {
+		var tables []string
+		for _, table := range desired {
+			tables = append(tables, table.Name)
+		}
+
+		current, err = LiveSchema(db, tables)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	changes := DiffSchema(db, current, desired)
+	for _, warning := range changes.Warnings {
+		m.logger.Infof("Schema warning: %s", warning)
+	}
+
+	if len(changes.Up) == 0 {
+		return "", ErrorNoSchemaChanges
+	}
+
+	path, err := m.writeMigration(options, changes.Up, changes.Down, changes.Warnings)
+	if err != nil {
+		return "", err
+	}
+
+	if options.Snapshot != "" {
+		err = WriteSnapshot(options.Snapshot, desired)
+	}
+
+	return path, err
+}
+
+// ModelSchema returns the tables GORM would create on db for models.
+func ModelSchema(db *gorm.DB, models ...any) ([]TableSnapshot, error) {
+	var tables []TableSnapshot
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+
+		table := TableSnapshot{Name: stmt.Table, Indexes: []IndexSnapshot{}}
+		for _, dbName := range stmt.Schema.DBNames {
+			field := stmt.Schema.FieldsByDBName[dbName]
+			if field.IgnoreMigration {
+				continue
+			}
+
+			table.Columns = append(table.Columns, ColumnSnapshot{
+				Name:       dbName,
+				Type:       db.Migrator().FullDataTypeOf(field).SQL,
+				PrimaryKey: field.PrimaryKey,
+			})
+		}
+
+		for _, index := range stmt.Schema.ParseIndexes() {
+			snapshot := IndexSnapshot{Name: index.Name, Unique: index.Class == "UNIQUE"}
+			for _, option := range index.Fields {
+				if option.Field != nil {
+					snapshot.Columns = append(snapshot.Columns, option.DBName)
+				} else {
+					snapshot.Columns = append(snapshot.Columns, option.Expression)
+				}
+			}
+			table.Indexes = append(table.Indexes, snapshot)
+		}
+		for _, unique := range stmt.Schema.ParseUniqueConstraints() {
+			table.Indexes = append(table.Indexes, IndexSnapshot{Name: unique.Name, Columns: []string{unique.Field.DBName}, Unique: true})
+		}
+		sortIndexes(table.Indexes)
+
+		tables = append(tables, table)
+	}
+
+	return tables, nil
+}
+
+// LiveSchema reads the given tables from db. Tables which do not exist are left out.
+func LiveSchema(db *gorm.DB, tables []string) ([]TableSnapshot, error) {
+	var snapshots []TableSnapshot
+
+	for _, name := range tables {
+		if !db.Migrator().HasTable(name) {
+			continue
+		}
+
+		columnTypes, err := db.Migrator().ColumnTypes(name)
+		if err != nil {
+			return nil, err
+		}
+
+		indexes, err := db.Migrator().GetIndexes(name)
+		if err != nil {
+			return nil, err
+		}
+
+		table := TableSnapshot{Name: name, Indexes: []IndexSnapshot{}}
+		for _, columnType := range columnTypes {
+			primaryKey, _ := columnType.PrimaryKey()
+			table.Columns = append(table.Columns, ColumnSnapshot{
+				Name:       columnType.Name(),
+				Type:       strings.ToLower(columnType.DatabaseTypeName()),
+				PrimaryKey: primaryKey,
+			})
+
+			// Some databases only report unique constraints on the column.
+			if unique, _ := columnType.Unique(); unique && !primaryKey {
+				table.Indexes = append(table.Indexes, IndexSnapshot{Columns: []string{columnType.Name()}, Unique: true})
+			}
+		}
+
+		for _, index := range indexes {
+			if primaryKey, _ := index.PrimaryKey(); primaryKey {
+				continue
+			}
+
+			unique, _ := index.Unique()
+			table.Indexes = append(table.Indexes, IndexSnapshot{Name: index.Name(), Columns: index.Columns(), Unique: unique})
+		}
+		sortIndexes(table.Indexes)
+
+		snapshots = append(snapshots, table)
+	}
+
+	return snapshots, nil
+}
+
+// LoadSnapshot reads a schema snapshot written by WriteSnapshot.
+func LoadSnapshot(path string) ([]TableSnapshot, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []TableSnapshot
+	err = json.Unmarshal(content, &tables)
+
+	return tables, err
+}
+
+// WriteSnapshot records a schema, so later migrations can be generated without a live database.
+func WriteSnapshot(path string, tables []TableSnapshot) error {
+	content, err := json.MarshalIndent(tables, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(content, '\n'), 0o644)
+}
+
+// DiffSchema returns the statements needed to move db from the current schema to the desired one.
+func DiffSchema(db *gorm.DB, current []TableSnapshot, desired []TableSnapshot) SchemaChanges {
+	var changes SchemaChanges
+	quote := (&gorm.Statement{DB: db}).Quote
+
+	currentTables := map[string]TableSnapshot{}
+	for _, table := range current {
+		currentTables[table.Name] = table
+	}
+
+	desiredTables := map[string]bool{}
+	for _, table := range desired {
+		desiredTables[table.Name] = true
+
+		existing, ok := currentTables[table.Name]
+		if !ok {
+			changes.Up = append(changes.Up, createTableStatement(quote, table))
+			for _, index := range table.Indexes {
+				changes.Up = append(changes.Up, createIndexStatement(quote, table.Name, index))
+			}
+			changes.Down = append(changes.Down, fmt.Sprintf("DROP TABLE %s", quote(table.Name)))
+			continue
+		}
+
+		existingColumns := map[string]ColumnSnapshot{}
+		for _, column := range existing.Columns {
+			existingColumns[column.Name] = column
+		}
+
+		desiredColumns := map[string]bool{}
+		for _, column := range table.Columns {
+			desiredColumns[column.Name] = true
+
+			existingColumn, ok := existingColumns[column.Name]
+			if !ok {
+				changes.Up = append(changes.Up, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", quote(table.Name), quote(column.Name), column.Type))
+				changes.Down = append(changes.Down, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", quote(table.Name), quote(column.Name)))
+
+				definition := strings.ToUpper(column.Type)
+				if strings.Contains(definition, "NOT NULL") && !strings.Contains(definition, "DEFAULT") {
+					changes.Warnings = append(changes.Warnings, fmt.Sprintf("column %s.%s is added as NOT NULL without a default, which fails when the table has rows", table.Name, column.Name))
+				}
+				continue
+			}
+
+			if baseType(existingColumn.Type) != baseType(column.Type) {
+				changes.Warnings = append(changes.Warnings, fmt.Sprintf("column %s.%s is %s in the schema, but %s in the model", table.Name, column.Name, existingColumn.Type, column.Type))
+			}
+		}
+
+		for _, column := range existing.Columns {
+			if !desiredColumns[column.Name] {
+				changes.Warnings = append(changes.Warnings, fmt.Sprintf("column %s.%s is not in the model, and has not been dropped", table.Name, column.Name))
+			}
+		}
+
+		if existing.Indexes == nil {
+			changes.Warnings = append(changes.Warnings, fmt.Sprintf("table %s has no indexes in the snapshot, so they have not been compared", table.Name))
+			continue
+		}
+
+		for _, index := range table.Indexes {
+			if !slices.ContainsFunc(existing.Indexes, index.matches) {
+				changes.Up = append(changes.Up, createIndexStatement(quote, table.Name, index))
+				changes.Down = append(changes.Down, dropIndexStatement(db, quote, table.Name, index))
+			}
+		}
+
+		for _, index := range existing.Indexes {
+			if !slices.ContainsFunc(table.Indexes, index.matches) {
+				changes.Warnings = append(changes.Warnings, fmt.Sprintf("index %s of table %s is not in the model, and has not been dropped", indexName(index), table.Name))
+			}
+		}
+	}
+
+	for _, table := range current {
+		if !desiredTables[table.Name] {
+			changes.Warnings = append(changes.Warnings, fmt.Sprintf("table %s is not in the models, and has not been dropped", table.Name))
+		}
+	}
+
+	// Changes are reverted in the opposite order.
+	for i, j := 0, len(changes.Down)-1; i < j; i, j = i+1, j-1 {
+		changes.Down[i], changes.Down[j] = changes.Down[j], changes.Down[i]
+	}
+	sort.Strings(changes.Warnings)
+
+	return changes
+}
+
+func createTableStatement(quote func(field interface{}) string, table TableSnapshot) string {
+	var definitions []string
+	var primaryKeys []string
+	hasPrimaryKeyInType := false
+
+	for _, column := range table.Columns {
+		definitions = append(definitions, fmt.Sprintf("%s %s", quote(column.Name), column.Type))
+
+		if column.PrimaryKey {
+			primaryKeys = append(primaryKeys, quote(column.Name))
+		}
+		if strings.Contains(strings.ToUpper(column.Type), "PRIMARY KEY") {
+			hasPrimaryKeyInType = true
+		}
+	}
+
+	if !hasPrimaryKeyInType && len(primaryKeys) > 0 {
+		definitions = append(definitions, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(primaryKeys, ",")))
+	}
+
+	return fmt.Sprintf("CREATE TABLE %s (%s)", quote(table.Name), strings.Join(definitions, ","))
+}
+
+// createIndexStatement creates an index, or the unique index which enforces a unique constraint.
+func createIndexStatement(quote func(field interface{}) string, table string, index IndexSnapshot) string {
+	columns := make([]string, len(index.Columns))
+	for i, column := range index.Columns {
+		columns[i] = quote(column)
+	}
+
+	kind := "INDEX"
+	if index.Unique {
+		kind = "UNIQUE INDEX"
+	}
+
+	return fmt.Sprintf("CREATE %s %s ON %s (%s)", kind, quote(index.Name), quote(table), strings.Join(columns, ","))
+}
+
+func dropIndexStatement(db *gorm.DB, quote func(field interface{}) string, table string, index IndexSnapshot) string {
+	// MySQL scopes index names to their table.
+	if db.Dialector.Name() == "mysql" {
+		return fmt.Sprintf("DROP INDEX %s ON %s", quote(index.Name), quote(table))
+	}
+
+	return fmt.Sprintf("DROP INDEX %s", quote(index.Name))
+}
+
+// indexName names an index in warnings, by its columns when it has no name.
+func indexName(index IndexSnapshot) string {
+	if index.Name != "" {
+		return index.Name
+	}
+
+	return "(" + strings.Join(index.Columns, ",") + ")"
+}
+
+// sortIndexes orders indexes by name, as GORM parses them into a map.
+func sortIndexes(indexes []IndexSnapshot) {
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexName(indexes[i]) < indexName(indexes[j])
+	})
+}
+
+// baseType returns the lowercase type name of a column definition, without any constraints.
+func baseType(definition string) string {
+	fields := strings.Fields(strings.ToLower(definition))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return fields[0]
+}
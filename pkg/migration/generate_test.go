@@ -0,0 +1,153 @@
+package migration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type generatedThing struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+type generatedThingV2 struct {
+	ID    uint `gorm:"primaryKey"`
+	Name  string
+	Count int
+}
+
+func (generatedThingV2) TableName() string {
+	return "generated_things"
+}
+
+type generatedIndexedThing struct {
+	ID    uint   `gorm:"primaryKey"`
+	Name  string `gorm:"index"`
+	Email string `gorm:"unique"`
+}
+
+type generatedIndexedThingV2 struct {
+	ID    uint   `gorm:"primaryKey"`
+	Name  string `gorm:"index"`
+	Email string `gorm:"unique"`
+	Code  string `gorm:"not null;uniqueIndex"`
+	Count int    `gorm:"not null;default:0"`
+}
+
+func (generatedIndexedThingV2) TableName() string {
+	return "generated_indexed_things"
+}
+
+func TestMigrator_Generate(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	assert.NoError(t, err)
+	dir := t.TempDir()
+
+	generate := func(model any) (string, error) {
+		migrator := NewMigrator()
+		migrator.TrackModels(model)
+		return migrator.Generate(db, GenerateOptions{Name: "things", Dir: dir, SQL: true})
+	}
+	apply := func() {
+		migrator := NewMigrator()
+		assert.NoError(t, migrator.RegisterFS(os.DirFS(dir), "."))
+		assert.NoError(t, migrator.Run(db))
+	}
+
+	path, err := generate(&generatedThing{})
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "001_things.up.sql"), path)
+	apply()
+	assert.True(t, db.Migrator().HasTable("generated_things"))
+
+	_, err = generate(&generatedThing{})
+	assert.ErrorIs(t, err, ErrorNoSchemaChanges)
+
+	path, err = generate(&generatedThingV2{})
+	assert.NoError(t, err)
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "ADD COLUMN `count` integer")
+	apply()
+	assert.True(t, db.Migrator().HasColumn("generated_things", "count"))
+
+	// Going back to the old model reports the extra column, rather than dropping it.
+	_, err = generate(&generatedThing{})
+	assert.ErrorIs(t, err, ErrorNoSchemaChanges)
+}
+
+func TestMigrator_GenerateFromSnapshot(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	assert.NoError(t, err)
+	dir := t.TempDir()
+	snapshot := filepath.Join(dir, "schema.json")
+
+	migrator := NewMigrator()
+	migrator.TrackModels(&generatedThing{}, &generatedThing{})
+	path, err := migrator.Generate(db, GenerateOptions{Name: "things", Dir: dir, Package: "things", Snapshot: snapshot})
+	assert.NoError(t, err)
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "package things")
+	assert.Contains(t, string(content), "CREATE TABLE `generated_things`")
+	assert.Contains(t, string(content), "DROP TABLE `generated_things`")
+
+	// The database is never migrated, but the snapshot already contains the table.
+	_, err = migrator.Generate(db, GenerateOptions{Name: "things", Dir: dir, Snapshot: snapshot})
+	assert.ErrorIs(t, err, ErrorNoSchemaChanges)
+
+	migrator = NewMigrator()
+	migrator.TrackModels(&generatedThingV2{})
+	path, err = migrator.Generate(db, GenerateOptions{Name: "count", Dir: dir, Package: "things", Snapshot: snapshot})
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "002_count.go"), path)
+}
+
+func TestMigrator_GenerateIndexes(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	assert.NoError(t, err)
+	dir := t.TempDir()
+
+	generate := func(model any) string {
+		migrator := NewMigrator()
+		migrator.TrackModels(model)
+		path, err := migrator.Generate(db, GenerateOptions{Name: "things", Dir: dir, SQL: true})
+		assert.NoError(t, err)
+
+		migrator = NewMigrator()
+		assert.NoError(t, migrator.RegisterFS(os.DirFS(dir), "."))
+		assert.NoError(t, migrator.Run(db))
+
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		return string(content)
+	}
+
+	content := generate(&generatedIndexedThing{})
+	assert.Contains(t, content, "CREATE INDEX `idx_generated_indexed_things_name` ON `generated_indexed_things` (`name`)")
+	assert.Contains(t, content, "CREATE UNIQUE INDEX `uni_generated_indexed_things_email` ON `generated_indexed_things` (`email`)")
+	assert.True(t, db.Migrator().HasIndex("generated_indexed_things", "idx_generated_indexed_things_name"))
+	assert.Error(t, db.Exec("INSERT INTO generated_indexed_things (name, email) VALUES ('a', 'a'), ('b', 'a')").Error)
+
+	// Columns added as NOT NULL without a default fail on tables with rows, so they are called out.
+	content = generate(&generatedIndexedThingV2{})
+	assert.Contains(t, content, "-- Warning: column generated_indexed_things.code is added as NOT NULL without a default")
+	assert.NotContains(t, content, "column generated_indexed_things.count")
+	assert.Contains(t, content, "CREATE UNIQUE INDEX `idx_generated_indexed_things_code` ON `generated_indexed_things` (`code`)")
+	assert.NotContains(t, content, "`idx_generated_indexed_things_name`")
+
+	down, err := os.ReadFile(filepath.Join(dir, "002_things.down.sql"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(down), "DROP INDEX `idx_generated_indexed_things_code`")
+
+	migrator := NewMigrator()
+	migrator.TrackModels(&generatedIndexedThingV2{})
+	_, err = migrator.Generate(db, GenerateOptions{Name: "things", Dir: dir, SQL: true})
+	assert.ErrorIs(t, err, ErrorNoSchemaChanges)
+}
@@ -3,129 +3,123 @@ package migration
 import (
 	"crypto/md5"
 	"encoding/hex"
-	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path"
 	"runtime"
 	"strings"
 	"time"
 
-	"github.com/labstack/gommon/log"
 	"gorm.io/gorm"
 )
 
+// DefaultTableName is the meta table used by migrators that do not specify one.
+const DefaultTableName = "__migrations_meta"
+
+// Default is the migrator used by the package level functions.
+var Default = NewMigrator()
+
 var (
+	// RegisteredMigrations are added to the Default migrator the next time it is used. It does not list the
+	// migrations registered in other ways.
+	//
+	// Deprecated: Use Register or Default.Add, and Default.Migrations to list the migrations.
 	RegisteredMigrations []Migration
-	MigrationsTableName  = "__migrations_meta"
+
+	// MigrationsTableName changes the meta table of the Default migrator the next time it is used.
+	//
+	// Deprecated: Use Default.SetTableName and Default.TableName.
+	MigrationsTableName = DefaultTableName
 )
 
 type MigratorFunc func(db *gorm.DB) error
 
+// TransactionMode decides how a migration is wrapped in a transaction.
+// The meta row of a migration is always written together with the migration itself.
+type TransactionMode int
+
+const (
+	// TransactionDefault uses the default mode of the Migrator.
+	TransactionDefault TransactionMode = iota
+
+	// TransactionPerMigration runs the migration in its own transaction.
+	TransactionPerMigration
+
+	// TransactionBatch runs the migration in a transaction shared with the adjacent batch migrations.
+	TransactionBatch
+
+	// TransactionNone runs the migration directly on the database.
+	// Use this for statements which cannot run inside a transaction.
+	TransactionNone
+)
+
 type Migration struct {
 	Name     string
 	FullPath string
 
 	NoChecksum bool
 
+	// Checksum is used instead of hashing the file at FullPath when set.
+	Checksum string
+
+	// Transaction decides how the migration is wrapped in a transaction.
+	// The zero value uses the default mode of the Migrator.
+	Transaction TransactionMode
+
 	Up   MigratorFunc
 	Down MigratorFunc
 }
 
-func Register(up MigratorFunc, down MigratorFunc) {
-	// Convert the file name into a migration name.
-	_, filePath, _, _ := runtime.Caller(1) // skip 1 for call site stack level for consumer
-	_, fileName := path.Split(filePath)
-	migrationName := strings.Split(fileName, ".")[0]
+type MigrationsMeta struct {
+	Name     string
+	Checksum string
 
-	RegisteredMigrations = append(RegisteredMigrations, Migration{
-		Name:     migrationName,
-		FullPath: filePath,
-		Up:       up,
-		Down:     down,
-	})
+	Timestamp time.Time
 }
 
-func RegisterSystemMigration(name string, up MigratorFunc, down MigratorFunc) {
-	RegisteredMigrations = append(RegisteredMigrations, Migration{
-		Name:       name,
-		NoChecksum: true,
-		Up:         up,
-		Down:       down,
-	})
+// Register adds a migration to the Default migrator, named after the file it is called from.
+func Register(up MigratorFunc, down MigratorFunc) {
+	Default.Add(migrationFromCaller(up, down, 2))
 }
 
-type MigrationsMeta struct {
-	Name     string
-	Checksum string
+// RegisterSystemMigration adds a named migration without a checksum to the Default migrator.
+func RegisterSystemMigration(name string, up MigratorFunc, down MigratorFunc) {
+	Default.RegisterSystemMigration(name, up, down)
+}
 
-	Timestamp time.Time
+// RegisterFS adds the SQL migrations in dir of fsys to the Default migrator. See Migrator.RegisterFS.
+func RegisterFS(fsys fs.FS, dir string) error {
+	return Default.RegisterFS(fsys, dir)
 }
 
+// RunMigrations applies the migrations registered on the Default migrator.
 func RunMigrations(db *gorm.DB) error {
-	err := db.Transaction(func(tx *gorm.DB) error {
-		log.Infof("Ensuring meta table exists")
-		metaErr := tx.Table(MigrationsTableName).AutoMigrate(MigrationsMeta{})
-		if metaErr != nil {
-			return metaErr
-		}
-
-		log.Infof("Will migrate over %d migrations", len(RegisteredMigrations))
-
-		for i, migration := range RegisteredMigrations {
-			log.Infof("(%d) Migrating %s", i, migration.Name)
-
-			var sum string
-
-			// Check if we have a matching migration with a correct checksum, and skip if that is the case.
-			var existing []MigrationsMeta
-			tx.Table(MigrationsTableName).Limit(1).Find(&existing, "name = ?", migration.Name)
-			if len(existing) > 0 {
-				// Allows system migrations to ignore checksums
-				if !migration.NoChecksum {
-					sum, _ = getMigrationChecksum(migration)
-					if sum == "" {
-						return fmt.Errorf("checksum was empty for migration %s", migration.Name)
-					}
-					if existing[0].Checksum != sum {
-						return fmt.Errorf("checksum mismatch for migration %s: %s != %s", migration.Name, sum, existing[0].Checksum)
-					}
-				} else {
-					sum = time.Now().UTC().String()
-				}
-
-				log.Infof(">\t DONE skip %s", migration.Name)
-				continue
-			} else {
-				sum, _ = getMigrationChecksum(migration)
-			}
-
-			err := migration.Up(tx)
-			if err != nil {
-				log.Errorf(">\t FAIL migration %s: %s", migration.Name, err)
-				return err
-			}
-
-			// Insert the migration into the meta table
-			timeStamp := time.Now().UTC()
-			tx.Table(MigrationsTableName).Create(MigrationsMeta{
-				Name:      migration.Name,
-				Checksum:  sum,
-				Timestamp: timeStamp,
-			})
-			log.Infof("Added migration meta for %s with checksum %s", migration.Name, sum)
-
-			log.Infof(">\t DONE migration %s", migration.Name)
-		}
-		return nil
-	})
-
-	return err
+	return Default.Run(db)
+}
+
+// migrationFromCaller builds a migration named after the file found skip levels up the call stack.
+func migrationFromCaller(up MigratorFunc, down MigratorFunc, skip int) Migration {
+	// Convert the file name into a migration name.
+	_, filePath, _, _ := runtime.Caller(skip)
+	_, fileName := path.Split(filePath)
+	migrationName := strings.Split(fileName, ".")[0]
+
+	return Migration{
+		Name:     migrationName,
+		FullPath: filePath,
+		Up:       up,
+		Down:     down,
+	}
 }
 
 // getMigrationChecksum will get the MD5 hash of the migration specified.
 // This is used to avoid applying migrations incorrectly.
 func getMigrationChecksum(migration Migration) (string, error) {
+	if migration.Checksum != "" {
+		return migration.Checksum, nil
+	}
+
 	file, err := os.Open(migration.FullPath)
 	if err != nil {
 		return "", err
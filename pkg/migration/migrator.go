@@ -0,0 +1,335 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/labstack/gommon/log"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrorUnknownMigration = errors.New("migration is not registered")
+	ErrorNoDownMigration  = errors.New("migration has no down migration")
+)
+
+// Logger is the logging interface used by a Migrator.
+type Logger interface {
+	Infof(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// gommonLogger writes to the global gommon logger, which is what sas uses elsewhere.
+type gommonLogger struct{}
+
+func (gommonLogger) Infof(format string, args ...interface{}) {
+	log.Infof(format, args...)
+}
+
+func (gommonLogger) Errorf(format string, args ...interface{}) {
+	log.Errorf(format, args...)
+}
+
+// Migrator holds a set of migrations, and applies them to a database.
+// Each Migrator has its own registry and meta table, so several can be used in one process.
+// Operations are serialized within a process only: nothing stops two processes, such as the replicas of a
+// service, from migrating the same database at once. Run migrations from a single process then, such as a
+// deploy job, and start the replicas without running migrations.
+type Migrator struct {
+	tableName       string
+	logger          Logger
+	transactionMode TransactionMode
+
+	mu         sync.Mutex
+	migrations []Migration
+	models     []any
+
+	// The deprecated package variables last taken on by the Default migrator, see lock.
+	legacyTableName  string
+	legacyMigrations int
+}
+
+// Option configures a Migrator.
+type Option func(m *Migrator)
+
+// WithTableName sets the table used to record applied migrations.
+func WithTableName(name string) Option {
+	return func(m *Migrator) {
+		m.tableName = name
+	}
+}
+
+// WithLogger sets the logger used while migrating.
+func WithLogger(logger Logger) Option {
+	return func(m *Migrator) {
+		m.logger = logger
+	}
+}
+
+// WithTransactionMode sets the mode used by migrations which do not specify one.
+// Migrators use TransactionPerMigration unless configured otherwise.
+func WithTransactionMode(mode TransactionMode) Option {
+	return func(m *Migrator) {
+		m.transactionMode = mode
+	}
+}
+
+// NewMigrator creates a Migrator with no migrations registered.
+func NewMigrator(options ...Option) *Migrator {
+	m := &Migrator{
+		tableName:       DefaultTableName,
+		legacyTableName: DefaultTableName,
+		logger:          gommonLogger{},
+		transactionMode: TransactionPerMigration,
+	}
+
+	for _, option := range options {
+		option(m)
+	}
+
+	return m
+}
+
+// TableName returns the table used to record applied migrations.
+func (m *Migrator) TableName() string {
+	m.lock()
+	defer m.mu.Unlock()
+
+	return m.tableName
+}
+
+// lock locks the migrator. The Default migrator also takes on the changes to the deprecated package variables,
+// so code written against them keeps working.
+func (m *Migrator) lock() {
+	m.mu.Lock()
+	if m != Default {
+		return
+	}
+
+	if MigrationsTableName != m.legacyTableName {
+		m.tableName = MigrationsTableName
+		m.legacyTableName = MigrationsTableName
+	}
+	if len(RegisteredMigrations) > m.legacyMigrations {
+		m.migrations = append(m.migrations, RegisteredMigrations[m.legacyMigrations:]...)
+	}
+	m.legacyMigrations = len(RegisteredMigrations)
+}
+
+// SetTableName changes the table used to record applied migrations.
+func (m *Migrator) SetTableName(name string) {
+	m.lock()
+	defer m.mu.Unlock()
+
+	m.tableName = name
+}
+
+// Register adds a migration, named after the file it is called from.
+func (m *Migrator) Register(up MigratorFunc, down MigratorFunc) {
+	m.Add(migrationFromCaller(up, down, 2))
+}
+
+// RegisterSystemMigration adds a named migration which is not checksummed.
+func (m *Migrator) RegisterSystemMigration(name string, up MigratorFunc, down MigratorFunc) {
+	m.Add(Migration{
+		Name:       name,
+		NoChecksum: true,
+		Up:         up,
+		Down:       down,
+	})
+}
+
+// RegisterFS registers every NNN_name.up.sql / NNN_name.down.sql pair found in dir of fsys.
+// The migration name is the file name without the suffix, so SQL migrations interleave with Go migrations by name.
+//
+// A file may contain multiple statements separated by semicolons. Statements containing semicolons themselves,
+// such as triggers, can be wrapped in "-- +sas StatementBegin" and "-- +sas StatementEnd".
+// An up file containing "-- +sas NoTransaction" is run outside of any transaction.
+func (m *Migrator) RegisterFS(fsys fs.FS, dir string) error {
+	migrations, err := loadSQLMigrations(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	m.Add(migrations...)
+
+	return nil
+}
+
+// Add registers fully specified migrations.
+func (m *Migrator) Add(migrations ...Migration) {
+	m.lock()
+	defer m.mu.Unlock()
+
+	m.migrations = append(m.migrations, migrations...)
+}
+
+// Migrations returns the registered migrations, ordered by name.
+// Go and SQL migrations are interleaved by this ordering.
+func (m *Migrator) Migrations() []Migration {
+	m.lock()
+	defer m.mu.Unlock()
+
+	return sortedMigrations(m.migrations)
+}
+
+// sortedMigrations returns a copy of migrations, ordered by name.
+func sortedMigrations(migrations []Migration) []Migration {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	return sorted
+}
+
+// Run applies every registered migration that has not been applied yet.
+// Only one operation per Migrator can be in progress at a time.
+func (m *Migrator) Run(db *gorm.DB) error {
+	m.lock()
+	defer m.mu.Unlock()
+
+	return m.up(db, "")
+}
+
+// up applies the pending migrations, up to and including target. An empty target applies all of them.
+func (m *Migrator) up(db *gorm.DB, target string) error {
+	if err := m.ensureMetaTable(db); err != nil {
+		return err
+	}
+
+	migrations := sortedMigrations(m.migrations)
+	if target != "" {
+		index := indexOfMigration(migrations, target)
+		if index < 0 {
+			return fmt.Errorf("%w: %s", ErrorUnknownMigration, target)
+		}
+		migrations = migrations[:index+1]
+	}
+	m.logger.Infof("Will migrate over %d migrations", len(migrations))
+
+	return m.execute(db, migrations, m.apply)
+}
+
+func (m *Migrator) ensureMetaTable(db *gorm.DB) error {
+	m.logger.Infof("Ensuring meta table exists")
+	return db.Table(m.tableName).AutoMigrate(MigrationsMeta{})
+}
+
+// execute calls step for each migration, wrapping it in a transaction according to its TransactionMode.
+func (m *Migrator) execute(db *gorm.DB, migrations []Migration, step func(tx *gorm.DB, migration Migration) error) error {
+	// Consecutive batch migrations share a transaction, which is committed before any other migration runs.
+	var batch []Migration
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for _, migration := range batch {
+				if err := step(tx, migration); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		batch = nil
+
+		return err
+	}
+
+	for _, migration := range migrations {
+		mode := migration.Transaction
+		if mode == TransactionDefault {
+			mode = m.transactionMode
+		}
+
+		if mode == TransactionBatch {
+			batch = append(batch, migration)
+			continue
+		}
+
+		if err := flush(); err != nil {
+			return err
+		}
+
+		var err error
+		switch mode {
+		case TransactionNone:
+			err = step(db, migration)
+		default:
+			err = db.Transaction(func(tx *gorm.DB) error {
+				return step(tx, migration)
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return flush()
+}
+
+// apply runs a single migration and records it in the meta table, unless it has already been applied.
+func (m *Migrator) apply(tx *gorm.DB, migration Migration) error {
+	m.logger.Infof("Migrating %s", migration.Name)
+
+	// Check if we have a matching migration with a correct checksum, and skip if that is the case.
+	var existing []MigrationsMeta
+	if err := tx.Table(m.tableName).Limit(1).Find(&existing, "name = ?", migration.Name).Error; err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		// Allows system migrations to ignore checksums
+		if !migration.NoChecksum {
+			sum, _ := getMigrationChecksum(migration)
+			if sum == "" {
+				return fmt.Errorf("checksum was empty for migration %s", migration.Name)
+			}
+			if existing[0].Checksum != sum {
+				return fmt.Errorf("checksum mismatch for migration %s: %s != %s", migration.Name, sum, existing[0].Checksum)
+			}
+		}
+
+		m.logger.Infof(">\t DONE skip %s", migration.Name)
+		return nil
+	}
+
+	err := migration.Up(tx)
+	if err != nil {
+		m.logger.Errorf(">\t FAIL migration %s: %s", migration.Name, err)
+		return err
+	}
+
+	err = m.record(tx, migration)
+	if err != nil {
+		return err
+	}
+
+	m.logger.Infof(">\t DONE migration %s", migration.Name)
+	return nil
+}
+
+// record inserts the migration into the meta table.
+func (m *Migrator) record(tx *gorm.DB, migration Migration) error {
+	sum, _ := getMigrationChecksum(migration)
+
+	timeStamp := time.Now().UTC()
+	metaTx := tx.Table(m.tableName).Create(MigrationsMeta{
+		Name:      migration.Name,
+		Checksum:  sum,
+		Timestamp: timeStamp,
+	})
+	if metaTx.Error != nil {
+		return metaTx.Error
+	}
+	m.logger.Infof("Added migration meta for %s with checksum %s", migration.Name, sum)
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package migration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMigrator_Register(t *testing.T) {
+	migrator := NewMigrator()
+	migrator.Register(func(db *gorm.DB) error {
+		return nil
+	}, nil)
+
+	migrations := migrator.Migrations()
+	assert.Len(t, migrations, 1)
+	assert.Equal(t, "migrator_test", migrations[0].Name)
+	assert.Empty(t, Default.Migrations())
+}
+
+func TestMigrator_Independent(t *testing.T) {
+	t.Parallel()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	assert.NoError(t, err)
+
+	counts := map[string]int{}
+	newMigrator := func(table string) *Migrator {
+		migrator := NewMigrator(WithTableName(table))
+		migrator.RegisterSystemMigration("001_shared_name", func(db *gorm.DB) error {
+			counts[table]++
+			return nil
+		}, nil)
+		return migrator
+	}
+
+	first := newMigrator("__first_meta")
+	second := newMigrator("__second_meta")
+
+	assert.NoError(t, first.Run(db))
+	assert.NoError(t, second.Run(db))
+	assert.NoError(t, first.Run(db))
+
+	assert.Equal(t, 1, counts["__first_meta"])
+	assert.Equal(t, 1, counts["__second_meta"])
+
+	var applied int64
+	db.Table("__second_meta").Count(&applied)
+	assert.Equal(t, int64(1), applied)
+}
+
+func TestMigrator_TransactionModes(t *testing.T) {
+	failing := errors.New("failing migration")
+
+	run := func(options ...Option) (*gorm.DB, error) {
+		db, err := gorm.Open(sqlite.Open(":memory:"))
+		assert.NoError(t, err)
+
+		migrator := NewMigrator(options...)
+		migrator.Add(
+			Migration{Name: "001_create", Checksum: "1", Up: func(db *gorm.DB) error {
+				return db.Exec("CREATE TABLE things (name TEXT)").Error
+			}},
+			Migration{Name: "002_insert", Checksum: "2", Up: func(db *gorm.DB) error {
+				return db.Exec("INSERT INTO things (name) VALUES ('a')").Error
+			}},
+			Migration{Name: "003_fail", Checksum: "3", Up: func(db *gorm.DB) error {
+				return failing
+			}},
+		)
+
+		return db, migrator.Run(db)
+	}
+
+	// Each migration commits on its own, so only the failing one is rolled back.
+	db, err := run()
+	assert.ErrorIs(t, err, failing)
+	var names []string
+	assert.NoError(t, db.Table("things").Pluck("name", &names).Error)
+	assert.Equal(t, []string{"a"}, names)
+	var applied int64
+	db.Table(DefaultTableName).Count(&applied)
+	assert.Equal(t, int64(2), applied)
+
+	// A batch is rolled back as a whole.
+	db, err = run(WithTransactionMode(TransactionBatch))
+	assert.ErrorIs(t, err, failing)
+	assert.False(t, db.Migrator().HasTable("things"))
+	db.Table(DefaultTableName).Count(&applied)
+	assert.Equal(t, int64(0), applied)
+}
+
+func TestMigrator_MetaReadFails(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	assert.NoError(t, err)
+
+	migrator := NewMigrator()
+	ran := false
+	migrator.RegisterSystemMigration("001_once", func(db *gorm.DB) error {
+		ran = true
+		return nil
+	}, nil)
+
+	// A migration is not applied when it can not be told whether it already has been.
+	failed := errors.New("meta table could not be read")
+	table := migrator.TableName()
+	assert.NoError(t, db.Callback().Query().Before("gorm:query").Register("fail_meta", func(tx *gorm.DB) {
+		if tx.Statement.Table == table {
+			_ = tx.AddError(failed)
+		}
+	}))
+
+	assert.ErrorIs(t, migrator.Run(db), failed)
+	assert.False(t, ran)
+}
+
+func TestMigrator_DeprecatedVariables(t *testing.T) {
+	migrations := Default.Migrations()
+	t.Cleanup(func() {
+		RegisteredMigrations = nil
+		MigrationsTableName = DefaultTableName
+
+		Default.mu.Lock()
+		Default.migrations = migrations
+		Default.legacyMigrations = 0
+		Default.mu.Unlock()
+		Default.SetTableName(DefaultTableName)
+	})
+
+	RegisteredMigrations = append(RegisteredMigrations, Migration{Name: "001_legacy", NoChecksum: true})
+	MigrationsTableName = "legacy_migrations"
+
+	assert.Equal(t, "legacy_migrations", Default.TableName())
+	assert.Len(t, Default.Migrations(), len(migrations)+1)
+	assert.Len(t, Default.Migrations(), len(migrations)+1)
+
+	// The setters of Default still take precedence until the variable changes again.
+	Default.SetTableName("newer_migrations")
+	assert.Equal(t, "newer_migrations", Default.TableName())
+}
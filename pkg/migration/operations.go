@@ -0,0 +1,269 @@
+package migration
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Status describes a migration, and whether it has been applied.
+type Status struct {
+	Name string
+
+	Applied   bool
+	AppliedAt time.Time
+
+	// Changed is set when the applied migration no longer matches its recorded checksum.
+	Changed bool
+
+	// Missing is set when the migration has been applied, but is no longer registered.
+	Missing bool
+}
+
+// Status reports every registered migration, followed by applied migrations which are no longer registered.
+func (m *Migrator) Status(db *gorm.DB) ([]Status, error) {
+	m.lock()
+	defer m.mu.Unlock()
+
+	applied, err := m.appliedMeta(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	seen := map[string]bool{}
+	for _, migration := range sortedMigrations(m.migrations) {
+		seen[migration.Name] = true
+
+		status := Status{Name: migration.Name}
+		if meta, ok := applied[migration.Name]; ok {
+			status.Applied = true
+			status.AppliedAt = meta.Timestamp
+
+			if !migration.NoChecksum {
+				sum, _ := getMigrationChecksum(migration)
+				status.Changed = sum != meta.Checksum
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	var missing []Status
+	for name, meta := range applied {
+		if !seen[name] {
+			missing = append(missing, Status{Name: name, Applied: true, AppliedAt: meta.Timestamp, Missing: true})
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].Name < missing[j].Name
+	})
+
+	return append(statuses, missing...), nil
+}
+
+// Plan returns the migrations which Run would apply, in order.
+func (m *Migrator) Plan(db *gorm.DB) ([]Migration, error) {
+	m.lock()
+	defer m.mu.Unlock()
+
+	applied, err := m.appliedMeta(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range sortedMigrations(m.migrations) {
+		if _, ok := applied[migration.Name]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// Down reverts the last steps applied migrations, newest first.
+func (m *Migrator) Down(db *gorm.DB, steps int) error {
+	m.lock()
+	defer m.mu.Unlock()
+
+	_, err := m.down(db, steps)
+	return err
+}
+
+// To migrates up to and including the named migration if it is pending,
+// or reverts every migration applied after it if it has already been applied.
+func (m *Migrator) To(db *gorm.DB, name string) error {
+	m.lock()
+	defer m.mu.Unlock()
+
+	migrations := sortedMigrations(m.migrations)
+	if indexOfMigration(migrations, name) < 0 {
+		return fmt.Errorf("%w: %s", ErrorUnknownMigration, name)
+	}
+
+	applied, err := m.appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	index := indexOfMigration(applied, name)
+	if index < 0 {
+		return m.up(db, name)
+	}
+
+	_, err = m.down(db, len(applied)-index-1)
+	return err
+}
+
+// Redo reverts the last applied migration, and applies it again.
+func (m *Migrator) Redo(db *gorm.DB) error {
+	m.lock()
+	defer m.mu.Unlock()
+
+	reverted, err := m.down(db, 1)
+	if err != nil {
+		return err
+	}
+
+	if len(reverted) == 0 {
+		return nil
+	}
+
+	return m.execute(db, reverted, m.apply)
+}
+
+// Baseline records the migrations up to and including target as applied, without running them.
+// This is used to adopt an existing database. An empty target marks every registered migration.
+func (m *Migrator) Baseline(db *gorm.DB, target string) error {
+	m.lock()
+	defer m.mu.Unlock()
+
+	if err := m.ensureMetaTable(db); err != nil {
+		return err
+	}
+
+	migrations := sortedMigrations(m.migrations)
+	if target != "" {
+		index := indexOfMigration(migrations, target)
+		if index < 0 {
+			return fmt.Errorf("%w: %s", ErrorUnknownMigration, target)
+		}
+		migrations = migrations[:index+1]
+	}
+
+	applied, err := m.appliedMeta(db)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, migration := range migrations {
+			if _, ok := applied[migration.Name]; ok {
+				continue
+			}
+
+			if err := m.record(tx, migration); err != nil {
+				return err
+			}
+			m.logger.Infof(">\t BASELINE %s", migration.Name)
+		}
+		return nil
+	})
+}
+
+// down reverts the last steps applied migrations, and returns them in the order they were applied.
+func (m *Migrator) down(db *gorm.DB, steps int) ([]Migration, error) {
+	applied, err := m.appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if steps > len(applied) {
+		steps = len(applied)
+	}
+	if steps <= 0 {
+		return nil, nil
+	}
+
+	toRevert := applied[len(applied)-steps:]
+
+	reversed := make([]Migration, len(toRevert))
+	for i, migration := range toRevert {
+		reversed[len(toRevert)-1-i] = migration
+	}
+
+	return toRevert, m.execute(db, reversed, m.revert)
+}
+
+// revert runs the down migration, and removes it from the meta table.
+func (m *Migrator) revert(tx *gorm.DB, migration Migration) error {
+	m.logger.Infof("Reverting %s", migration.Name)
+
+	if migration.Down == nil {
+		return fmt.Errorf("%w: %s", ErrorNoDownMigration, migration.Name)
+	}
+
+	err := migration.Down(tx)
+	if err != nil {
+		m.logger.Errorf(">\t FAIL revert %s: %s", migration.Name, err)
+		return err
+	}
+
+	metaTx := tx.Table(m.tableName).Where("name = ?", migration.Name).Delete(&MigrationsMeta{})
+	if metaTx.Error != nil {
+		return metaTx.Error
+	}
+
+	m.logger.Infof(">\t DONE revert %s", migration.Name)
+	return nil
+}
+
+// appliedMeta returns the meta rows of the applied migrations by name.
+func (m *Migrator) appliedMeta(db *gorm.DB) (map[string]MigrationsMeta, error) {
+	applied := map[string]MigrationsMeta{}
+	if !db.Migrator().HasTable(m.tableName) {
+		return applied, nil
+	}
+
+	var metas []MigrationsMeta
+	tx := db.Table(m.tableName).Find(&metas)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	for _, meta := range metas {
+		applied[meta.Name] = meta
+	}
+
+	return applied, nil
+}
+
+// appliedMigrations returns the registered migrations which have been applied, ordered by name.
+func (m *Migrator) appliedMigrations(db *gorm.DB) ([]Migration, error) {
+	meta, err := m.appliedMeta(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range sortedMigrations(m.migrations) {
+		if _, ok := meta[migration.Name]; ok {
+			applied = append(applied, migration)
+		}
+	}
+
+	return applied, nil
+}
+
+func indexOfMigration(migrations []Migration, name string) int {
+	for i, migration := range migrations {
+		if migration.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,183 @@
+package migration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// GenerateOptions describes a migration file to create.
+type GenerateOptions struct {
+	// Name of the migration, which is prefixed with the next migration number.
+	Name string
+
+	// Dir to create the migration in, defaults to the working directory.
+	Dir string
+
+	// Package name of a Go migration, defaults to the name of Dir.
+	Package string
+
+	// SQL creates a pair of .up.sql and .down.sql files instead of a Go file.
+	SQL bool
+
+	// Snapshot is the path of a schema snapshot, used by Generate instead of the live database schema.
+	Snapshot string
+}
+
+var (
+	migrationNumberPattern = regexp.MustCompile(`^(\d+)_`)
+	invalidNameCharacters  = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+const goMigrationTemplate = `package %s
+
+import (
+	"github.com/imthatgin/sas/pkg/migration"
+	"gorm.io/gorm"
+)
+
+func init() {
+	migration.Register(func(db *gorm.DB) error {
+%s	}, func(db *gorm.DB) error {
+%s	})
+}
+`
+
+// Exec runs each statement in order, stopping at the first error.
+func Exec(db *gorm.DB, statements ...string) error {
+	return execStatements(statements)(db)
+}
+
+// Create scaffolds an empty migration file, numbered after the highest migration found in the directory or registry.
+// It returns the path of the created Go file, or of the up file for SQL migrations.
+func (m *Migrator) Create(options GenerateOptions) (string, error) {
+	return m.writeMigration(options, nil, nil, nil)
+}
+
+// writeMigration writes a migration of the up and down statements. The warnings are written as comments above
+// the up statements.
+func (m *Migrator) writeMigration(options GenerateOptions, up []string, down []string, warnings []string) (string, error) {
+	dir := options.Dir
+	if dir == "" {
+		dir = "."
+	}
+
+	name := strings.Trim(invalidNameCharacters.ReplaceAllString(strings.ToLower(options.Name), "_"), "_")
+	if name == "" {
+		return "", fmt.Errorf("invalid migration name %q", options.Name)
+	}
+
+	number, err := m.nextMigrationNumber(dir)
+	if err != nil {
+		return "", err
+	}
+	base := filepath.Join(dir, fmt.Sprintf("%03d_%s", number, name))
+
+	if options.SQL {
+		upPath := base + sqlUpSuffix
+		if err := writeNewFile(upPath, warningComments("-- ", warnings)+sqlMigrationBody(up, "up")); err != nil {
+			return "", err
+		}
+		if err := writeNewFile(base+sqlDownSuffix, sqlMigrationBody(down, "down")); err != nil {
+			return "", err
+		}
+		return upPath, nil
+	}
+
+	packageName := options.Package
+	if packageName == "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return "", err
+		}
+		packageName = invalidNameCharacters.ReplaceAllString(strings.ToLower(filepath.Base(abs)), "")
+	}
+
+	goPath := base + ".go"
+	content := fmt.Sprintf(goMigrationTemplate, packageName, warningComments("\t\t// ", warnings)+goMigrationBody(up), goMigrationBody(down))
+	return goPath, writeNewFile(goPath, content)
+}
+
+func warningComments(prefix string, warnings []string) string {
+	var comments strings.Builder
+	for _, warning := range warnings {
+		comments.WriteString(prefix + "Warning: " + warning + "\n")
+	}
+
+	return comments.String()
+}
+
+func sqlMigrationBody(statements []string, direction string) string {
+	if len(statements) == 0 {
+		return fmt.Sprintf("-- Write the %s migration here.\n", direction)
+	}
+
+	var body strings.Builder
+	for _, statement := range statements {
+		body.WriteString(statement)
+		body.WriteString(";\n")
+	}
+
+	return body.String()
+}
+
+func goMigrationBody(statements []string) string {
+	if len(statements) == 0 {
+		return "\t\treturn nil\n"
+	}
+
+	var body strings.Builder
+	body.WriteString("\t\treturn migration.Exec(db,\n")
+	for _, statement := range statements {
+		body.WriteString("\t\t\t" + strconv.Quote(statement) + ",\n")
+	}
+	body.WriteString("\t\t)\n")
+
+	return body.String()
+}
+
+func (m *Migrator) nextMigrationNumber(dir string) (int, error) {
+	highest := 0
+	observe := func(name string) {
+		match := migrationNumberPattern.FindStringSubmatch(name)
+		if match == nil {
+			return
+		}
+		if number, err := strconv.Atoi(match[1]); err == nil && number > highest {
+			highest = number
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	for _, entry := range entries {
+		observe(entry.Name())
+	}
+
+	for _, migration := range m.Migrations() {
+		observe(migration.Name)
+	}
+
+	return highest + 1, nil
+}
+
+func writeNewFile(path string, content string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.WriteString(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,230 @@
+package migration
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	sqlUpSuffix   = ".up.sql"
+	sqlDownSuffix = ".down.sql"
+
+	directivePrefix         = "-- +sas"
+	directiveNoTransaction  = "NoTransaction"
+	directiveStatementBegin = "StatementBegin"
+	directiveStatementEnd   = "StatementEnd"
+)
+
+// loadSQLMigrations reads every NNN_name.up.sql / NNN_name.down.sql pair found in dir of fsys.
+func loadSQLMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ups := map[string]string{}
+	downs := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		name := entry.Name()
+		switch {
+		case strings.HasSuffix(name, sqlUpSuffix):
+			ups[strings.TrimSuffix(name, sqlUpSuffix)] = path.Join(dir, name)
+		case strings.HasSuffix(name, sqlDownSuffix):
+			downs[strings.TrimSuffix(name, sqlDownSuffix)] = path.Join(dir, name)
+		}
+	}
+
+	for name := range downs {
+		if _, ok := ups[name]; !ok {
+			return nil, fmt.Errorf("sql migration %s has a down file but no up file", name)
+		}
+	}
+
+	var migrations []Migration
+	for name, upPath := range ups {
+		migration, err := loadSQLMigration(fsys, name, upPath, downs[name])
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, migration)
+	}
+
+	return migrations, nil
+}
+
+// loadSQLMigration reads and parses an up file and an optional down file into a Migration.
+func loadSQLMigration(fsys fs.FS, name string, upPath string, downPath string) (Migration, error) {
+	hash := md5.New()
+
+	upContent, err := fs.ReadFile(fsys, upPath)
+	if err != nil {
+		return Migration{}, err
+	}
+	hash.Write(upContent)
+
+	up, err := parseSQL(string(upContent))
+	if err != nil {
+		return Migration{}, fmt.Errorf("sql migration %s: %w", upPath, err)
+	}
+
+	migration := Migration{
+		Name:        name,
+		FullPath:    upPath,
+		Transaction: up.transaction,
+		Up:          execStatements(up.statements),
+	}
+
+	if downPath != "" {
+		downContent, err := fs.ReadFile(fsys, downPath)
+		if err != nil {
+			return Migration{}, err
+		}
+		hash.Write(downContent)
+
+		down, err := parseSQL(string(downContent))
+		if err != nil {
+			return Migration{}, fmt.Errorf("sql migration %s: %w", downPath, err)
+		}
+
+		migration.Down = execStatements(down.statements)
+	}
+
+	migration.Checksum = hex.EncodeToString(hash.Sum(nil))
+
+	return migration, nil
+}
+
+// execStatements returns a MigratorFunc executing each statement in order.
+func execStatements(statements []string) MigratorFunc {
+	return func(db *gorm.DB) error {
+		for _, statement := range statements {
+			tx := db.Exec(statement)
+			if tx.Error != nil {
+				return tx.Error
+			}
+		}
+
+		return nil
+	}
+}
+
+type parsedSQL struct {
+	statements  []string
+	transaction TransactionMode
+}
+
+// parseSQL splits a SQL file into statements, and reads any sas directives in it.
+func parseSQL(content string) (parsedSQL, error) {
+	var result parsedSQL
+
+	var chunk strings.Builder
+	inBlock := false
+
+	for _, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, directivePrefix) {
+			chunk.WriteString(line)
+			continue
+		}
+
+		switch directive := strings.TrimSpace(strings.TrimPrefix(trimmed, directivePrefix)); directive {
+		case directiveNoTransaction:
+			result.transaction = TransactionNone
+
+		case directiveStatementBegin:
+			if inBlock {
+				return result, fmt.Errorf("nested %s", directiveStatementBegin)
+			}
+			result.statements = append(result.statements, splitStatements(chunk.String())...)
+			chunk.Reset()
+			inBlock = true
+
+		case directiveStatementEnd:
+			if !inBlock {
+				return result, fmt.Errorf("%s without %s", directiveStatementEnd, directiveStatementBegin)
+			}
+			if statement := strings.TrimSpace(chunk.String()); statement != "" {
+				result.statements = append(result.statements, statement)
+			}
+			chunk.Reset()
+			inBlock = false
+
+		default:
+			return result, fmt.Errorf("unknown directive %q", directive)
+		}
+	}
+
+	if inBlock {
+		return result, fmt.Errorf("%s without %s", directiveStatementBegin, directiveStatementEnd)
+	}
+	result.statements = append(result.statements, splitStatements(chunk.String())...)
+
+	return result, nil
+}
+
+// splitStatements splits sql on semicolons, ignoring those inside quotes and comments.
+// Parts that only contain whitespace and comments are dropped.
+func splitStatements(sql string) []string {
+	var statements []string
+
+	var quote byte
+	inLineComment := false
+	inBlockComment := false
+	hasContent := false
+	start := 0
+
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+
+		switch {
+		case inLineComment:
+			if ch == '\n' {
+				inLineComment = false
+			}
+		case inBlockComment:
+			if ch == '*' && i+1 < len(sql) && sql[i+1] == '/' {
+				inBlockComment = false
+				i++
+			}
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			inLineComment = true
+		case ch == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			inBlockComment = true
+			i++
+		case ch == ';':
+			if hasContent {
+				statements = append(statements, strings.TrimSpace(sql[start:i+1]))
+			}
+			hasContent = false
+			start = i + 1
+		default:
+			if ch == '\'' || ch == '"' || ch == '`' {
+				quote = ch
+			}
+			if ch != ' ' && ch != '\t' && ch != '\n' && ch != '\r' {
+				hasContent = true
+			}
+		}
+	}
+
+	if hasContent {
+		statements = append(statements, strings.TrimSpace(sql[start:]))
+	}
+
+	return statements
+}
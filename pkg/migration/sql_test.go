@@ -0,0 +1,88 @@
+package migration
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestParseSQL_MultipleStatements(t *testing.T) {
+	parsed, err := parseSQL(`
+-- first statement
+CREATE TABLE a (id INTEGER, note TEXT DEFAULT 'a;b');
+/* second; statement */
+INSERT INTO a (id) VALUES (1);
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, TransactionDefault, parsed.transaction)
+	assert.Len(t, parsed.statements, 2)
+	assert.Contains(t, parsed.statements[0], "'a;b'")
+	assert.Contains(t, parsed.statements[1], "INSERT INTO a")
+}
+
+func TestParseSQL_Directives(t *testing.T) {
+	parsed, err := parseSQL(`-- +sas NoTransaction
+CREATE TABLE a (id INTEGER);
+-- +sas StatementBegin
+CREATE TRIGGER a_trigger AFTER INSERT ON a BEGIN
+	UPDATE a SET id = id + 1;
+END;
+-- +sas StatementEnd
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, TransactionNone, parsed.transaction)
+	assert.Len(t, parsed.statements, 2)
+	assert.Contains(t, parsed.statements[1], "UPDATE a SET id = id + 1;\nEND;")
+
+	_, err = parseSQL("-- +sas StatementBegin\nSELECT 1;")
+	assert.Error(t, err)
+
+	_, err = parseSQL("-- +sas Unknown\nSELECT 1;")
+	assert.Error(t, err)
+}
+
+func TestRegisterFS(t *testing.T) {
+	migrator := NewMigrator()
+	migrator.Add(Migration{
+		Name:     "002_go_migration",
+		Checksum: "static",
+		Up: func(db *gorm.DB) error {
+			return db.Exec("INSERT INTO things (name) VALUES ('from go')").Error
+		},
+	})
+
+	fsys := fstest.MapFS{
+		"migrations/001_things.up.sql":      {Data: []byte("CREATE TABLE things (name TEXT);")},
+		"migrations/001_things.down.sql":    {Data: []byte("DROP TABLE things;")},
+		"migrations/003_more_things.up.sql": {Data: []byte("INSERT INTO things (name) VALUES ('a'); INSERT INTO things (name) VALUES ('b');")},
+		"migrations/004_no_tx.up.sql":       {Data: []byte("-- +sas NoTransaction\nINSERT INTO things (name) VALUES ('c');")},
+		"migrations/README.md":              {Data: []byte("ignored")},
+	}
+	assert.NoError(t, migrator.RegisterFS(fsys, "migrations"))
+	assert.Len(t, migrator.Migrations(), 4)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	assert.NoError(t, err)
+	assert.NoError(t, migrator.Run(db))
+
+	var names []string
+	db.Table("things").Pluck("name", &names)
+	assert.Equal(t, []string{"from go", "a", "b", "c"}, names)
+
+	// Running again must skip every migration, as the checksums still match.
+	assert.NoError(t, migrator.Run(db))
+	db.Table("things").Pluck("name", &names)
+	assert.Len(t, names, 4)
+}
+
+func TestRegisterFS_MissingUp(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/001_things.down.sql": {Data: []byte("DROP TABLE things;")},
+	}
+	assert.Error(t, NewMigrator().RegisterFS(fsys, "migrations"))
+}
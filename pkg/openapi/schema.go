@@ -0,0 +1,266 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	invalidComponentCharacters = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// Schemas converts Go types into JSON schemas, following the rules of encoding/json.
+// Named struct types are stored as components, and referenced with $ref.
+type Schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func NewSchemas() *Schemas {
+	return &Schemas{
+		components: map[string]*Schema{},
+		names:      map[reflect.Type]string{},
+	}
+}
+
+// Components returns every named schema created so far.
+func (s *Schemas) Components() map[string]*Schema {
+	return s.components
+}
+
+// For returns the schema of t.
+func (s *Schemas) For(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	if t.Kind() == reflect.Pointer {
+		return nullable(s.For(t.Elem()))
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		// The encoding is unknown, so any value is accepted.
+		return &Schema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Format: "int64", Minimum: new(float64)}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32", Minimum: new(float64)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.For(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.For(t.Elem())}
+	case reflect.Struct:
+		return s.structSchema(t)
+	}
+
+	return &Schema{}
+}
+
+// Ref returns the $ref of a component schema.
+func Ref(name string) string {
+	return "#/components/schemas/" + name
+}
+
+func (s *Schemas) structSchema(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return s.objectSchema(t)
+	}
+
+	if name, ok := s.names[t]; ok {
+		return &Schema{Ref: Ref(name)}
+	}
+
+	name := s.componentName(t)
+	s.names[t] = name
+
+	// The placeholder allows recursive types to reference themselves while the schema is built.
+	s.components[name] = &Schema{}
+	*s.components[name] = *s.objectSchema(t)
+
+	return &Schema{Ref: Ref(name)}
+}
+
+func (s *Schemas) componentName(t reflect.Type) string {
+	base := invalidComponentCharacters.ReplaceAllString(t.Name(), "_")
+	name := base
+
+	for i := 2; ; i++ {
+		if _, taken := s.components[name]; !taken {
+			return name
+		}
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+}
+
+func (s *Schemas) objectSchema(t reflect.Type) *Schema {
+	schema := &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{},
+	}
+
+	for _, field := range JSONFields(t) {
+		schema.Properties[field.Name] = s.For(field.Type)
+		if field.Required {
+			schema.Required = append(schema.Required, field.Name)
+		}
+	}
+
+	return schema
+}
+
+func nullable(schema *Schema) *Schema {
+	switch schemaType := schema.Type.(type) {
+	case string:
+		schema.Type = []string{schemaType, "null"}
+		return schema
+	case nil:
+		if schema.Ref == "" && schema.AnyOf == nil {
+			// An empty schema already accepts null.
+			return schema
+		}
+	}
+
+	return &Schema{AnyOf: []*Schema{schema, {Type: "null"}}}
+}
+
+// JSONField is a struct field, as encoded by encoding/json.
+type JSONField struct {
+	Name string
+	Type reflect.Type
+
+	// OmitEmpty is set by the omitempty option of the json tag.
+	OmitEmpty bool
+
+	// Required is set by a "required" rule in a validate or binding tag.
+	Required bool
+}
+
+// JSONFields returns the fields of the struct type t as they appear in JSON.
+// Embedded structs without a json name are flattened, and their fields are shadowed by outer fields,
+// like encoding/json does.
+func JSONFields(t reflect.Type) []JSONField {
+	type candidate struct {
+		field JSONField
+		depth int
+	}
+
+	var candidates []candidate
+	var walk func(t reflect.Type, depth int)
+	walk = func(t reflect.Type, depth int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+
+			name, options, _ := strings.Cut(tag, ",")
+
+			fieldType := field.Type
+			if field.Anonymous && name == "" {
+				if fieldType.Kind() == reflect.Pointer {
+					fieldType = fieldType.Elem()
+				}
+				if fieldType.Kind() == reflect.Struct {
+					walk(fieldType, depth+1)
+					continue
+				}
+			}
+
+			if !field.IsExported() {
+				continue
+			}
+
+			if name == "" {
+				name = field.Name
+			}
+
+			candidates = append(candidates, candidate{
+				depth: depth,
+				field: JSONField{
+					Name:      name,
+					Type:      field.Type,
+					OmitEmpty: hasOption(options, "omitempty") || hasOption(options, "omitzero"),
+					Required:  hasRule(field.Tag.Get("validate"), "required") || hasRule(field.Tag.Get("binding"), "required"),
+				},
+			})
+		}
+	}
+
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct {
+		walk(t, 0)
+	}
+
+	shallowest := map[string]int{}
+	for _, c := range candidates {
+		if depth, ok := shallowest[c.field.Name]; !ok || c.depth < depth {
+			shallowest[c.field.Name] = c.depth
+		}
+	}
+
+	var fields []JSONField
+	added := map[string]bool{}
+	for _, c := range candidates {
+		if c.depth == shallowest[c.field.Name] && !added[c.field.Name] {
+			added[c.field.Name] = true
+			fields = append(fields, c.field)
+		}
+	}
+
+	return fields
+}
+
+func hasOption(options string, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasRule(tag string, rule string) bool {
+	for _, r := range strings.Split(tag, ",") {
+		if strings.TrimSpace(r) == rule {
+			return true
+		}
+	}
+
+	return false
+}
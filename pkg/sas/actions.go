@@ -0,0 +1,127 @@
+package sas
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Action adds an endpoint to the collection of a resource, such as POST /reports/regenerate.
+// The request body is bound to R and validated, and handler runs inside a transaction once can allows the call.
+// A non-nil result of handler is returned as JSON. A nil can denies every call. The decision of can is recorded
+// like those of the policy, as the predicate "Action <name>".
+// Errors of handler which do not wrap a sas error, such as ErrorResourceInvalidData, are reported as
+// ErrorDatabaseIssue, as the transaction has been rolled back.
+func Action[T any, R any](
+	mr *ModelResource[T],
+	name string,
+	can func(c Context) bool,
+	handler func(c Context, tx *gorm.DB, request *R) (any, error),
+) {
+	mr.addAction(name, "", reflect.TypeOf((*R)(nil)).Elem(), func(c Context) error {
+		if err := mr.authorize(c, OperationAction, nil, actionDecision(name, can != nil && can(c))); err != nil {
+			return err
+		}
+
+		var request R
+		if err := c.Bind(&request); err != nil {
+			return errors.Join(ErrorResourceInvalidData, err)
+		}
+		if err := validate(c, &request); err != nil {
+			return err
+		}
+
+		var result any
+		err := mr.database(c).Transaction(func(tx *gorm.DB) error {
+			var err error
+			result, err = handler(c, tx, &request)
+			return err
+		})
+
+		return actionResponse(c, result, err)
+	})
+}
+
+// ItemAction adds an endpoint to each entity of a resource, such as POST /orders/:id/cancel.
+// The entity is loaded with the list by ID query inside a transaction, which handler runs in once can allows
+// the call for the entity. The request body is bound to R and validated before the transaction, and a non-nil
+// result of handler is returned as JSON. A nil can denies every call. The decision of can is recorded like those
+// of the policy, as the predicate "Action <name>".
+func ItemAction[T any, R any](
+	mr *ModelResource[T],
+	name string,
+	can func(c Context, entity T) bool,
+	handler func(c Context, tx *gorm.DB, entity *T, request *R) (any, error),
+) {
+	mr.addAction(name, "/:id", reflect.TypeOf((*R)(nil)).Elem(), func(c Context) error {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return errors.Join(ErrorResourceInvalidID, err)
+		}
+
+		var request R
+		if err := c.Bind(&request); err != nil {
+			return errors.Join(ErrorResourceInvalidData, err)
+		}
+		if err := validate(c, &request); err != nil {
+			return err
+		}
+
+		var result any
+		err = mr.database(c).Transaction(func(tx *gorm.DB) error {
+			entity, err := mr.Queries.listByIdQuery(c, tx, uint(id))
+			if err != nil {
+				return err
+			}
+
+			if err := mr.authorize(c, OperationAction, entity, actionDecision(name, can != nil && can(c, *entity))); err != nil {
+				return err
+			}
+
+			result, err = handler(c, tx, entity, &request)
+			return err
+		})
+
+		return actionResponse(c, result, err)
+	})
+}
+
+// actionDecision is the decision of the can of an action.
+func actionDecision(name string, allowed bool) Decision {
+	return predicateDecision("Action "+strings.Trim(name, "/"), allowed)
+}
+
+func (mr *ModelResource[T]) addAction(name string, base string, requestType reflect.Type, handler HandlerFunc) {
+	name = strings.Trim(name, "/")
+
+	mr.actions = append(mr.actions, resourceRoute{
+		operation: OperationAction,
+		action:    name,
+		method:    http.MethodPost,
+		path:      base + "/" + name,
+		handler:   handler,
+
+		requestType: requestType,
+	})
+}
+
+// actionResponse writes the result of an action, or its error.
+func actionResponse(c Context, result any, err error) error {
+	if err != nil {
+		if _, _, ok := ErrorStatus(err); ok {
+			return err
+		}
+
+		return errors.Join(ErrorDatabaseIssue, err)
+	}
+
+	if result == nil {
+		return c.NoContent(http.StatusOK)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,118 @@
+package sas
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/imthatgin/sas/pkg/openapi"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type testCancelRequest struct {
+	Reason string `json:"reason"`
+}
+
+// testValidator rejects the requests with an invalid reason.
+type testValidator struct{}
+
+func (testValidator) Validate(i any) error {
+	if request, ok := i.(*testCancelRequest); ok && request.Reason == "invalid" {
+		return errors.New("reason is invalid")
+	}
+
+	return nil
+}
+
+func TestActions(t *testing.T) {
+	db := newTestOptionsDB(t)
+	assert.NoError(t, db.AutoMigrate(&testOptionsModel{}))
+	assert.NoError(t, db.Create(&testOptionsModel{Text: "open"}).Error)
+	assert.NoError(t, db.Create(&testOptionsModel{Text: "locked"}).Error)
+
+	resource := FromModel[testOptionsModel]("orders", db, NewPolicy[testOptionsModel](endpoints.GET))
+
+	ItemAction(&resource, "cancel",
+		func(c Context, entity testOptionsModel) bool {
+			return entity.Text != "locked"
+		},
+		func(c Context, tx *gorm.DB, entity *testOptionsModel, request *testCancelRequest) (any, error) {
+			if request.Reason == "" {
+				return nil, ErrorResourceInvalidData
+			}
+
+			entity.Text = "cancelled: " + request.Reason
+			if err := tx.Save(entity).Error; err != nil {
+				return nil, err
+			}
+			if request.Reason == "rollback" {
+				return nil, errors.New("changed my mind")
+			}
+
+			return entity, nil
+		})
+
+	Action(&resource, "count", nil, func(c Context, tx *gorm.DB, request *struct{}) (any, error) {
+		return nil, nil
+	})
+
+	log := NewDecisionLog(10)
+	e := echo.New()
+	e.Validator = testValidator{}
+	s, err := New(e, db, []Provider{&resource}, WithoutMigrations(), WithDecisionLog(log))
+	assert.NoError(t, err)
+
+	post := func(target string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post("/orders/1/cancel", `{"reason":"late"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Text":"cancelled: late"`)
+
+	assert.Equal(t, http.StatusBadRequest, post("/orders/1/cancel", `{}`).Code)
+	assert.Equal(t, http.StatusForbidden, post("/orders/2/cancel", `{"reason":"late"}`).Code)
+	assert.Equal(t, http.StatusNotFound, post("/orders/3/cancel", `{"reason":"late"}`).Code)
+	assert.Equal(t, http.StatusForbidden, post("/orders/count", `{}`).Code)
+
+	// The requests are validated, and the decisions of can are recorded.
+	assert.Equal(t, http.StatusBadRequest, post("/orders/1/cancel", `{"reason":"invalid"}`).Code)
+	records := log.Records()
+	assert.Equal(t, OperationAction, records[0].Operation)
+	assert.Equal(t, "Action count", records[0].Rule)
+	assert.False(t, records[0].Allowed)
+	assert.Equal(t, uint(2), records[1].EntityID)
+	assert.Equal(t, "denied by Action cancel", records[1].Reason)
+
+	// The transaction is rolled back when the handler fails.
+	assert.Equal(t, http.StatusInternalServerError, post("/orders/1/cancel", `{"reason":"rollback"}`).Code)
+	var order testOptionsModel
+	assert.NoError(t, db.First(&order, 1).Error)
+	assert.Equal(t, "cancelled: late", order.Text)
+
+	routes := s.Resources()[0].Routes
+	assert.Equal(t, RouteDescription{
+		Operation:   OperationAction,
+		Method:      http.MethodPost,
+		Path:        "/orders/:id/cancel",
+		Action:      "cancel",
+		RequestType: routes[2].RequestType,
+	}, routes[2])
+	assert.Equal(t, "testCancelRequest", routes[2].RequestType.Name())
+	assert.Equal(t, "/orders/count", routes[3].Path)
+
+	doc := s.OpenAPI(openapi.Info{Title: "test", Version: "1"})
+	cancel := doc.Paths["/orders/{id}/cancel"].Post
+	assert.Equal(t, "cancelOrders", cancel.OperationID)
+	assert.NotNil(t, cancel.RequestBody)
+	assert.Nil(t, doc.Paths["/orders/count"].Post.RequestBody)
+}
@@ -0,0 +1,146 @@
+package sas
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/imthatgin/sas/pkg/migration"
+	"gorm.io/gorm"
+)
+
+const (
+	// APIKeyTableName is the table API keys are stored in.
+	APIKeyTableName = "__sas_api_keys"
+
+	// APIKeyMigrationName is the name of the system migration which creates the API key table.
+	APIKeyMigrationName = "000_sas_api_keys"
+)
+
+// APIKey is a key for the principal with PrincipalID. Only the SHA-256 hash of the key is stored.
+// Roles and Scopes are comma separated lists.
+type APIKey struct {
+	ID   uint   `gorm:"primaryKey" json:"id"`
+	Name string `gorm:"size:255" json:"name"`
+	Hash string `gorm:"size:64;uniqueIndex" json:"-"`
+
+	PrincipalID string `gorm:"size:255" json:"principalId"`
+	Roles       string `gorm:"size:1024" json:"roles"`
+	Scopes      string `gorm:"size:1024" json:"scopes"`
+	Tenant      string `gorm:"size:255" json:"tenant"`
+
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+func (APIKey) TableName() string {
+	return APIKeyTableName
+}
+
+// APIKeys authenticates the API keys stored in a table, sent in the X-API-Key header by default.
+type APIKeys struct {
+	db     *gorm.DB
+	header string
+}
+
+// NewAPIKeys creates an authenticator for the API keys stored in db.
+func NewAPIKeys(db *gorm.DB) *APIKeys {
+	return &APIKeys{
+		db:     db,
+		header: "X-API-Key",
+	}
+}
+
+// Header sets the request header the key is read from.
+func (k *APIKeys) Header(header string) *APIKeys {
+	k.header = header
+	return k
+}
+
+// RegisterMigration adds the system migration which creates the API key table to m, unless it already has it.
+func (k *APIKeys) RegisterMigration(m *migration.Migrator) {
+	for _, existing := range m.Migrations() {
+		if existing.Name == APIKeyMigrationName {
+			return
+		}
+	}
+
+	m.RegisterSystemMigration(APIKeyMigrationName, func(db *gorm.DB) error {
+		return db.AutoMigrate(&APIKey{})
+	}, func(db *gorm.DB) error {
+		return db.Migrator().DropTable(&APIKey{})
+	})
+}
+
+// Create stores key with a new random secret, and returns the secret. The secret can not be read again.
+func (k *APIKeys) Create(key *APIKey) (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	secret := "sas_" + hex.EncodeToString(random)
+	key.Hash = hashAPIKey(secret)
+	if err := k.db.Create(key).Error; err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// Revoke stops a key from authenticating.
+func (k *APIKeys) Revoke(id uint) error {
+	return k.db.Model(&APIKey{}).Where("id = ?", id).Update("revoked_at", time.Now().UTC()).Error
+}
+
+// Authenticate reads the principal of the key in the header of the request.
+func (k *APIKeys) Authenticate(c Context) (*Principal, error) {
+	secret := c.Request().Header.Get(k.header)
+	if secret == "" {
+		return nil, nil
+	}
+
+	var key APIKey
+	err := k.db.Where("hash = ?", hashAPIKey(secret)).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("unknown API key")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if key.RevokedAt != nil {
+		return nil, errors.New("API key has been revoked")
+	}
+	if key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt) {
+		return nil, errors.New("API key has expired")
+	}
+
+	return &Principal{
+		ID:     key.PrincipalID,
+		Roles:  splitList(key.Roles),
+		Scopes: splitList(key.Scopes),
+		Tenant: key.Tenant,
+	}, nil
+}
+
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// splitList splits a comma separated list, without empty values.
+func splitList(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
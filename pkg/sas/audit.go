@@ -0,0 +1,294 @@
+package sas
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/imthatgin/sas/pkg/migration"
+	"gorm.io/gorm"
+)
+
+const (
+	// AuditTableName is the table audit records are written to.
+	AuditTableName = "__sas_audit"
+
+	// AuditMigrationName is the name of the system migration which creates the audit table.
+	AuditMigrationName = "000_sas_audit"
+)
+
+// AuditRecord is a single change to an entity. Before and After hold the entity as JSON, and are null for
+// creates and deletes respectively. Diff holds only the changed fields, as {"Field": {"before": ..., "after": ...}}.
+type AuditRecord struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	Resource string    `gorm:"index:idx_sas_audit_entity;size:255" json:"resource"`
+	EntityID uint      `gorm:"index:idx_sas_audit_entity" json:"entityId"`
+	Action   Operation `gorm:"size:32" json:"action"`
+
+	Actor     string `gorm:"size:255" json:"actor"`
+	RequestID string `gorm:"size:255" json:"requestId,omitempty"`
+
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+	Diff   json.RawMessage `json:"diff"`
+
+	Timestamp time.Time `gorm:"index" json:"timestamp"`
+}
+
+func (AuditRecord) TableName() string {
+	return AuditTableName
+}
+
+// Auditor writes an AuditRecord for every create, write and delete of the resources it is enabled for.
+type Auditor struct {
+	actor     func(c Context) string
+	requestID func(c Context) string
+}
+
+// NewAuditor creates an auditor which records the ID of the authenticated principal as the actor, and reads the
+// request ID from the X-Request-ID header of the request, or of the response as set by the RequestID middleware
+// of echo.
+func NewAuditor() *Auditor {
+	return &Auditor{
+		actor: func(c Context) string {
+			if p := PrincipalFrom(c); p != nil {
+				return p.ID
+			}
+
+			return ""
+		},
+		requestID: func(c Context) string {
+			if id := c.Request().Header.Get("X-Request-ID"); id != "" {
+				return id
+			}
+
+			return ResponseHeader(c).Get("X-Request-ID")
+		},
+	}
+}
+
+// Actor sets how the actor of a change is read from the request, such as the subject of a token.
+func (a *Auditor) Actor(actor func(c Context) string) *Auditor {
+	a.actor = actor
+	return a
+}
+
+// RequestID sets how the ID of a request is read, to correlate records with logs.
+func (a *Auditor) RequestID(requestID func(c Context) string) *Auditor {
+	a.requestID = requestID
+	return a
+}
+
+// RegisterMigration adds the system migration which creates the audit table to m, unless it already has it.
+func (a *Auditor) RegisterMigration(m *migration.Migrator) {
+	for _, existing := range m.Migrations() {
+		if existing.Name == AuditMigrationName {
+			return
+		}
+	}
+
+	m.RegisterSystemMigration(AuditMigrationName, func(db *gorm.DB) error {
+		return db.AutoMigrate(&AuditRecord{})
+	}, func(db *gorm.DB) error {
+		return db.Migrator().DropTable(&AuditRecord{})
+	})
+}
+
+// AuditEndpoint returns a read-only handler listing the audit records in db, newest first. The resource and entity
+// query parameters filter the records, and limit and offset paginate them. A nil can denies every call.
+func AuditEndpoint(db *gorm.DB, can func(c Context) bool) HandlerFunc {
+	return func(c Context) error {
+		if can == nil || !can(c) {
+			return ErrorResourceNoAccess
+		}
+
+		q := db.Model(&AuditRecord{}).Order("id DESC")
+		if resource := c.QueryParam("resource"); resource != "" {
+			q = q.Where("resource = ?", resource)
+		}
+		if entity := c.QueryParam("entity"); entity != "" {
+			id, err := strconv.ParseUint(entity, 10, 0)
+			if err != nil {
+				return errors.Join(ErrorResourceInvalidID, err)
+			}
+			q = q.Where("entity_id = ?", id)
+		}
+
+		q, err := Paginate(c, q)
+		if err != nil {
+			return err
+		}
+
+		records := []AuditRecord{}
+		if err := q.Find(&records).Error; err != nil {
+			return errors.Join(ErrorDatabaseIssue, err)
+		}
+
+		return c.JSON(http.StatusOK, records)
+	}
+}
+
+func (a *Auditor) record(c Context, tx *gorm.DB, resource string, action Operation, id uint, before json.RawMessage, after any) error {
+	record := AuditRecord{
+		Resource:  resource,
+		EntityID:  id,
+		Action:    action,
+		Actor:     a.actor(c),
+		RequestID: a.requestID(c),
+		Before:    before,
+		After:     json.RawMessage("null"),
+		Timestamp: time.Now().UTC(),
+	}
+
+	if after != nil {
+		encoded, err := json.Marshal(after)
+		if err != nil {
+			return err
+		}
+		record.After = encoded
+	}
+
+	diff, err := auditDiff(record.Before, record.After)
+	if err != nil {
+		return err
+	}
+	record.Diff = diff
+
+	return tx.Create(&record).Error
+}
+
+// auditDiff returns the top level fields which differ between two JSON objects.
+func auditDiff(before json.RawMessage, after json.RawMessage) (json.RawMessage, error) {
+	var beforeFields, afterFields map[string]any
+	if err := json.Unmarshal(before, &beforeFields); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(after, &afterFields); err != nil {
+		return nil, err
+	}
+
+	type change struct {
+		Before any `json:"before"`
+		After  any `json:"after"`
+	}
+
+	diff := map[string]change{}
+	for name, value := range afterFields {
+		if previous, ok := beforeFields[name]; !ok || !reflect.DeepEqual(previous, value) {
+			diff[name] = change{Before: beforeFields[name], After: value}
+		}
+	}
+	for name, value := range beforeFields {
+		if _, ok := afterFields[name]; !ok {
+			diff[name] = change{Before: value}
+		}
+	}
+
+	return json.Marshal(diff)
+}
+
+// Audit enables audit records for the creates, writes and deletes of the resource, including bulk ones.
+// The audit table is created by the migration added with Auditor.RegisterMigration, or by WithAuditor.
+func (mr *ModelResource[T]) Audit(auditor *Auditor) {
+	mr.auditor = auditor
+}
+
+// auditByDefault enables auditor for the resource, unless it has its own.
+func (mr *ModelResource[T]) auditByDefault(auditor *Auditor) {
+	if mr.auditor == nil {
+		mr.auditor = auditor
+	}
+}
+
+// ServeAudit serves the audit records read-only at path under the base path, such as /_sas/audit.
+// See AuditEndpoint.
+func (s *Server) ServeAudit(path string, can func(c Context) bool) {
+	s.serveGet(path, AuditEndpoint(s.db, can))
+}
+
+// tracked runs change, and records it when the resource is audited, the prior version when it keeps history, and
+// its event when it has an outbox or webhooks. The records are written in the same transaction as the change,
+// which is published once it succeeds.
+// before is the entity before the change, and nil for creates.
+// change returns the entity after the change, which is nil for deletes.
+func (mr *ModelResource[T]) tracked(c Context, db *gorm.DB, action Operation, before *T, change func(tx *gorm.DB) (*T, error)) error {
+	var after *T
+	var err error
+	if mr.auditor == nil && mr.outbox == nil && mr.webhooks == nil && (!mr.history || before == nil) {
+		after, err = change(db)
+	} else {
+		after, err = mr.record(c, db, action, before, change)
+	}
+	if err != nil {
+		return err
+	}
+
+	mr.publish(c, action, before, after)
+	if mr.outbox != nil {
+		mr.outbox.notify()
+	}
+	if mr.webhooks != nil {
+		mr.webhooks.notify()
+	}
+
+	return nil
+}
+
+func (mr *ModelResource[T]) record(c Context, db *gorm.DB, action Operation, before *T, change func(tx *gorm.DB) (*T, error)) (*T, error) {
+	// The entity is encoded up front, as writes change it in place.
+	beforeJSON := json.RawMessage("null")
+	if before != nil {
+		encoded, err := json.Marshal(before)
+		if err != nil {
+			return nil, errors.Join(ErrorDatabaseIssue, err)
+		}
+		beforeJSON = encoded
+	}
+
+	var after *T
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		after, err = change(tx)
+		if err != nil {
+			return err
+		}
+
+		var afterValue any
+		id := entityID(before)
+		if after != nil {
+			afterValue = after
+			id = entityID(after)
+		}
+
+		if mr.history && before != nil {
+			err := mr.recordHistory(tx, action, id, beforeJSON)
+			if errors.Is(err, ErrorResourceConflict) {
+				return err
+			}
+			if err != nil {
+				return errors.Join(ErrorDatabaseIssue, err)
+			}
+		}
+		if err := mr.writeOutbox(c, tx, action, before, after); err != nil {
+			return errors.Join(ErrorDatabaseIssue, err)
+		}
+		if err := mr.writeWebhooks(c, tx, action, before, after); err != nil {
+			return errors.Join(ErrorDatabaseIssue, err)
+		}
+		if mr.auditor == nil {
+			return nil
+		}
+
+		if err := mr.auditor.record(c, tx, mr.Name, action, id, beforeJSON, afterValue); err != nil {
+			return errors.Join(ErrorDatabaseIssue, err)
+		}
+
+		return nil
+	})
+
+	return after, err
+}
@@ -0,0 +1,103 @@
+package sas
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/imthatgin/sas/pkg/migration"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAudit(t *testing.T) {
+	db, resource := newTestEntries(t, endpoints.AllEndpoints)
+
+	auditor := NewAuditor().Actor(func(c Context) string {
+		return c.Request().Header.Get("X-User")
+	})
+
+	m := migration.NewMigrator()
+	s := newTestServer(t, db, []Provider{&resource}, WithMigrations(m, true), WithAuditor(auditor))
+	assert.True(t, db.Migrator().HasTable(AuditTableName))
+
+	s.ServeAudit("/_sas/audit", func(c Context) bool {
+		return c.Request().Header.Get("X-User") == "admin"
+	})
+
+	serve := func(method string, target string, body string) *httptest.ResponseRecorder {
+		return s.serve(method, target, body, "X-User", "admin", "X-Request-ID", "request-1")
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/entries", `{"Text":"first"}`).Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/entries/1", `{"Text":"second"}`).Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodDelete, "/entries/1", "").Code)
+
+	rec := serve(http.MethodGet, "/_sas/audit?resource=entries&entity=1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var records []AuditRecord
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &records))
+	assert.Len(t, records, 3)
+	assert.Equal(t, []Operation{OperationDelete, OperationWrite, OperationCreate},
+		[]Operation{records[0].Action, records[1].Action, records[2].Action})
+
+	write := records[1]
+	assert.Equal(t, uint(1), write.EntityID)
+	assert.Equal(t, "admin", write.Actor)
+	assert.Equal(t, "request-1", write.RequestID)
+	assert.Contains(t, string(write.Before), `"Text":"first"`)
+	assert.Contains(t, string(write.After), `"Text":"second"`)
+	assert.Contains(t, string(write.Diff), `"Text":{"before":"first","after":"second"}`)
+	assert.JSONEq(t, "null", string(records[0].After))
+
+	rec = serve(http.MethodGet, "/_sas/audit?resource=other", "")
+	assert.Equal(t, "[]\n", rec.Body.String())
+
+	assert.Equal(t, http.StatusForbidden, s.serve(http.MethodGet, "/_sas/audit", "").Code)
+
+	// Registering the migration again leaves a single audit migration.
+	auditor.RegisterMigration(m)
+	count := 0
+	for _, registered := range m.Migrations() {
+		if registered.Name == AuditMigrationName {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestServeAudit_Router(t *testing.T) {
+	db := newTestOptionsDB(t)
+	resource := FromModel[testOptionsModel]("entries", db, NewPolicy[testOptionsModel](endpoints.GET))
+
+	s := newTestServer(t, db, []Provider{&resource},
+		WithMigrations(migration.NewMigrator(), true),
+		WithAuditor(NewAuditor()),
+		WithBasePath("/api"),
+		WithAuthenticators(StaticTokens(map[string]*Principal{"auditor": {ID: "auditor", Roles: []string{"auditor"}}})),
+	)
+
+	// The audit log is served under the base path, and can sees the principal of the request.
+	s.ServeAudit("/_sas/audit", func(c Context) bool {
+		return HasRole("auditor")(PrincipalFrom(c))
+	})
+
+	serve := func(token string) int {
+		return s.serve(http.MethodGet, "/api/_sas/audit", "", "Authorization", "Bearer "+token).Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("auditor"))
+	assert.Equal(t, http.StatusForbidden, serve(""))
+}
+
+func TestNewAuditor_Actor(t *testing.T) {
+	auditor := NewAuditor()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+	assert.Equal(t, "", auditor.actor(c))
+
+	c.Set(contextKeyPrincipal, &Principal{ID: "admin"})
+	assert.Equal(t, "admin", auditor.actor(c))
+}
@@ -0,0 +1,431 @@
+package sas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	patch "github.com/geraldo-labs/merge-struct"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// BulkMode decides what happens to a bulk request when one of its items fails.
+type BulkMode string
+
+const (
+	// BulkAtomic applies every item or none of them, and is the default.
+	BulkAtomic BulkMode = "atomic"
+	// BulkPartial applies every item which succeeds, and reports the others.
+	BulkPartial BulkMode = "partial"
+)
+
+// BulkOptions limits the bulk endpoints of a resource.
+type BulkOptions struct {
+	// BatchSize is the number of rows inserted at once by atomic bulk creates.
+	BatchSize int
+	// MaxItems is the maximum number of items in a single request.
+	MaxItems int
+}
+
+// DefaultBulkOptions are used by resources which have not called ModelResource.BulkOptions.
+var DefaultBulkOptions = BulkOptions{
+	BatchSize: 100,
+	MaxItems:  1000,
+}
+
+// BulkResponse reports the result of each item of a bulk request, in the order of the request.
+type BulkResponse struct {
+	Mode       BulkMode         `json:"mode"`
+	RolledBack bool             `json:"rolledBack"`
+	Results    []BulkItemResult `json:"results"`
+}
+
+// BulkItemResult is the result of a single item. Status is the HTTP status the item would have had on its own,
+// or 424 for items of an atomic request which were rolled back or not attempted because another item failed.
+type BulkItemResult struct {
+	Index  int    `json:"index"`
+	ID     uint   `json:"id,omitempty"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkWriteItem is a single item of a bulk update, with changes matching the write bind type.
+type BulkWriteItem struct {
+	ID      uint            `json:"id"`
+	Changes json.RawMessage `json:"changes"`
+}
+
+var errorBulkItemFailed = errors.New("bulk item failed")
+
+// BulkOptions sets the batch size and item limit of the bulk endpoints.
+func (mr *ModelResource[T]) BulkOptions(options BulkOptions) {
+	mr.bulkOptions = options
+}
+
+func (mr *ModelResource[T]) bulk() BulkOptions {
+	options := mr.bulkOptions
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultBulkOptions.BatchSize
+	}
+	if options.MaxItems <= 0 {
+		options.MaxItems = DefaultBulkOptions.MaxItems
+	}
+
+	return options
+}
+
+func (mr *ModelResource[T]) bulkCreate(c Context) error {
+	if err := mr.authorize(c, OperationBulkCreate, nil, mr.Policy.create(c)); err != nil {
+		return err
+	}
+	if mr.createBindType == nil {
+		return ErrorFatalSetupNoBindType
+	}
+	// The create transformer reads the whole request, so it can not create the items of a bulk request.
+	if mr.createTransformer != nil {
+		return errors.Join(ErrorResourceInvalidData, fmt.Errorf("%s can not be created in bulk", mr.Name))
+	}
+
+	mode, err := bulkMode(c)
+	if err != nil {
+		return err
+	}
+
+	boundSlice := reflect.New(reflect.SliceOf(reflect.TypeOf(mr.createBindType)))
+	if err := c.Bind(boundSlice.Interface()); err != nil {
+		return errors.Join(ErrorResourceInvalidData, err)
+	}
+
+	items := boundSlice.Elem()
+	if err := mr.checkBulkSize(items.Len()); err != nil {
+		return err
+	}
+
+	// Each item is converted up front, so invalid items are reported before anything is written.
+	models := make([]T, items.Len())
+	itemErrors := make([]error, items.Len())
+	failed := false
+	for i := 0; i < items.Len(); i++ {
+		bound := items.Index(i).Addr().Interface()
+
+		if err := validate(c, bound); err != nil {
+			itemErrors[i] = err
+		} else if _, err := patch.Struct(&models[i], bound); err != nil {
+			itemErrors[i] = errors.Join(ErrorResourceInvalidData, err)
+		} else if err := assignTenant(c, &models[i]); err != nil {
+			itemErrors[i] = err
+		} else if err := mr.authorizeEntity(c, OperationBulkCreate, &models[i]); err != nil {
+			itemErrors[i] = err
+		}
+		failed = failed || itemErrors[i] != nil
+	}
+
+	results := newBulkResults(items.Len())
+	if mode == BulkAtomic {
+		if failed {
+			for i, err := range itemErrors {
+				if err != nil {
+					results[i] = bulkItemResult(i, 0, err)
+				}
+			}
+			return bulkResponse(c, mode, true, results)
+		}
+
+		err := mr.database(c).Transaction(func(tx *gorm.DB) error {
+			if err := tx.CreateInBatches(&models, mr.bulk().BatchSize).Error; err != nil {
+				return err
+			}
+
+			for i := range models {
+				if mr.auditor != nil {
+					err := mr.auditor.record(c, tx, mr.Name, OperationCreate, entityID(&models[i]), json.RawMessage("null"), &models[i])
+					if err != nil {
+						return err
+					}
+				}
+				if err := mr.writeOutbox(c, tx, OperationCreate, nil, &models[i]); err != nil {
+					return err
+				}
+				if err := mr.writeWebhooks(c, tx, OperationCreate, nil, &models[i]); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return errors.Join(ErrorDatabaseIssue, err)
+		}
+
+		for i := range models {
+			results[i] = bulkItemResult(i, entityID(&models[i]), nil)
+			mr.publish(c, OperationCreate, nil, &models[i])
+		}
+		if mr.outbox != nil {
+			mr.outbox.notify()
+		}
+		if mr.webhooks != nil {
+			mr.webhooks.notify()
+		}
+
+		return bulkResponse(c, mode, false, results)
+	}
+
+	return mr.runBulk(c, mode, results, func(tx *gorm.DB, i int) (uint, error) {
+		if itemErrors[i] != nil {
+			return 0, itemErrors[i]
+		}
+		err := mr.tracked(c, tx, OperationCreate, nil, func(tx *gorm.DB) (*T, error) {
+			if err := tx.Create(&models[i]).Error; err != nil {
+				return nil, errors.Join(ErrorDatabaseIssue, err)
+			}
+
+			return &models[i], nil
+		})
+
+		return entityID(&models[i]), err
+	})
+}
+
+func (mr *ModelResource[T]) bulkWrite(c Context) error {
+	if mr.writeBindType == nil {
+		return ErrorFatalSetupNoBindType
+	}
+
+	mode, err := bulkMode(c)
+	if err != nil {
+		return err
+	}
+
+	var items []BulkWriteItem
+	if err := c.Bind(&items); err != nil {
+		return errors.Join(ErrorResourceInvalidData, err)
+	}
+	if err := mr.checkBulkSize(len(items)); err != nil {
+		return err
+	}
+
+	return mr.runBulk(c, mode, newBulkResults(len(items)), func(tx *gorm.DB, i int) (uint, error) {
+		item := items[i]
+
+		bound := reflect.New(reflect.TypeOf(mr.writeBindType)).Interface()
+		if err := json.Unmarshal(item.Changes, bound); err != nil {
+			return item.ID, errors.Join(ErrorResourceInvalidData, err)
+		}
+		if err := validate(c, bound); err != nil {
+			return item.ID, err
+		}
+
+		entity, err := mr.Queries.listByIdQuery(c, tx, item.ID)
+		if err != nil {
+			return item.ID, err
+		}
+		if err := mr.authorize(c, OperationBulkWrite, entity, mr.Policy.writeById(c, *entity)); err != nil {
+			return item.ID, err
+		}
+		if err := mr.authorizeChange(c, OperationBulkWrite, entity, bound); err != nil {
+			return item.ID, err
+		}
+
+		err = mr.tracked(c, tx, OperationWrite, entity, func(tx *gorm.DB) (*T, error) {
+			if err := mr.Queries.writeByIdQuery(c, tx, entity, bound); err != nil {
+				return nil, errors.Join(ErrorDatabaseIssue, err)
+			}
+
+			return entity, nil
+		})
+
+		return item.ID, err
+	})
+}
+
+func (mr *ModelResource[T]) bulkDelete(c Context) error {
+	mode, err := bulkMode(c)
+	if err != nil {
+		return err
+	}
+
+	var ids []uint
+	for _, idStr := range strings.Split(c.QueryParam("ids"), ",") {
+		if idStr = strings.TrimSpace(idStr); idStr == "" {
+			continue
+		}
+
+		id, err := strconv.ParseUint(idStr, 10, 0)
+		if err != nil {
+			return errors.Join(ErrorResourceInvalidID, err)
+		}
+		ids = append(ids, uint(id))
+	}
+	if len(ids) == 0 {
+		return errors.Join(ErrorResourceInvalidID, errors.New("no ids given"))
+	}
+	if err := mr.checkBulkSize(len(ids)); err != nil {
+		return err
+	}
+
+	return mr.runBulk(c, mode, newBulkResults(len(ids)), func(tx *gorm.DB, i int) (uint, error) {
+		entity, err := mr.Queries.listByIdQuery(c, tx, ids[i])
+		if err != nil {
+			return ids[i], err
+		}
+		if err := mr.authorize(c, OperationBulkDelete, entity, mr.Policy.deleteById(c, *entity)); err != nil {
+			return ids[i], err
+		}
+
+		err = mr.tracked(c, tx, OperationDelete, entity, func(tx *gorm.DB) (*T, error) {
+			if err := mr.Queries.deleteByIdQuery(c, tx, *entity); err != nil {
+				return nil, errors.Join(ErrorDatabaseIssue, err)
+			}
+
+			return nil, nil
+		})
+
+		return ids[i], err
+	})
+}
+
+// runBulk applies every item inside a single transaction. In atomic mode the first failure rolls back the
+// transaction, and in partial mode each item is applied inside a savepoint so only the failed item is undone.
+func (mr *ModelResource[T]) runBulk(c Context, mode BulkMode, results []BulkItemResult, apply func(tx *gorm.DB, i int) (uint, error)) error {
+	rolledBack := false
+
+	// The events of the items are published once the transaction is committed.
+	holdEvents(c)
+
+	err := mr.database(c).Transaction(func(tx *gorm.DB) error {
+		for i := range results {
+			savepoint := fmt.Sprintf("sas_bulk_%d", i)
+			if mode == BulkPartial {
+				if err := tx.SavePoint(savepoint).Error; err != nil {
+					return err
+				}
+			}
+
+			id, err := apply(tx, i)
+			results[i] = bulkItemResult(i, id, err)
+			if err == nil {
+				continue
+			}
+
+			if mode == BulkAtomic {
+				rolledBack = true
+				for j := 0; j < i; j++ {
+					results[j].Status = http.StatusFailedDependency
+				}
+				return errorBulkItemFailed
+			}
+			if err := tx.RollbackTo(savepoint).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	mr.publishPending(c, err == nil)
+	if err != nil && !errors.Is(err, errorBulkItemFailed) {
+		return errors.Join(ErrorDatabaseIssue, err)
+	}
+
+	return bulkResponse(c, mode, rolledBack, results)
+}
+
+func (mr *ModelResource[T]) checkBulkSize(count int) error {
+	if max := mr.bulk().MaxItems; count > max {
+		return errors.Join(ErrorResourceInvalidData, fmt.Errorf("bulk requests are limited to %d items", max))
+	}
+
+	return nil
+}
+
+// bulkMode reads the mode query parameter, which defaults to BulkAtomic.
+func bulkMode(c Context) (BulkMode, error) {
+	switch mode := BulkMode(c.QueryParam("mode")); mode {
+	case "", BulkAtomic:
+		return BulkAtomic, nil
+	case BulkPartial:
+		return BulkPartial, nil
+	default:
+		return "", errors.Join(ErrorResourceInvalidData, fmt.Errorf("unknown bulk mode %q", mode))
+	}
+}
+
+func newBulkResults(count int) []BulkItemResult {
+	results := make([]BulkItemResult, count)
+	for i := range results {
+		results[i] = BulkItemResult{Index: i, Status: http.StatusFailedDependency}
+	}
+
+	return results
+}
+
+func bulkItemResult(index int, id uint, err error) BulkItemResult {
+	if err == nil {
+		return BulkItemResult{Index: index, ID: id, Status: http.StatusOK}
+	}
+
+	code, message, ok := ErrorStatus(err)
+	if !ok {
+		message = ErrorDatabaseIssue.Error()
+	}
+
+	return BulkItemResult{Index: index, ID: id, Status: code, Error: message}
+}
+
+// bulkResponse writes the results. A rolled back request has the status of the item which failed, and a partial
+// request with failed items has status 207.
+func bulkResponse(c Context, mode BulkMode, rolledBack bool, results []BulkItemResult) error {
+	code := http.StatusOK
+	for _, result := range results {
+		if result.Status == http.StatusOK || result.Status == http.StatusFailedDependency {
+			continue
+		}
+
+		if rolledBack {
+			code = result.Status
+			break
+		}
+		code = http.StatusMultiStatus
+	}
+
+	return c.JSON(code, BulkResponse{
+		Mode:       mode,
+		RolledBack: rolledBack,
+		Results:    results,
+	})
+}
+
+// validate runs the validator of echo on bound, when the context is from echo and a validator is registered.
+func validate(c Context, bound any) error {
+	validator, ok := c.(interface{ Validate(i interface{}) error })
+	if !ok {
+		return nil
+	}
+
+	err := validator.Validate(bound)
+	if err == nil || errors.Is(err, echo.ErrValidatorNotRegistered) {
+		return nil
+	}
+
+	return errors.Join(ErrorResourceInvalidData, err)
+}
+
+// entityID returns the ID field of an entity, such as the one of DefaultModel, or 0 when it has none.
+func entityID(entity any) uint {
+	value := reflect.Indirect(reflect.ValueOf(entity))
+	if value.Kind() != reflect.Struct {
+		return 0
+	}
+
+	field := value.FieldByName("ID")
+	if !field.IsValid() || !field.CanUint() {
+		return 0
+	}
+
+	return uint(field.Uint())
+}
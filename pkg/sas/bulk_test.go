@@ -0,0 +1,97 @@
+package sas
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulk(t *testing.T) {
+	db := newTestOptionsDB(t)
+	assert.NoError(t, db.AutoMigrate(&testOptionsModel{}))
+
+	policy := NewPolicy[testOptionsModel](endpoints.AllEndpoints | endpoints.PATCH | endpoints.BULK)
+	policy.
+		CanListAll(func(c Context) bool { return true }).
+		CanCreate(func(c Context) bool { return true }).
+		CanWriteById(func(c Context, entity testOptionsModel) bool { return entity.Text != "locked" }).
+		CanDeleteById(func(c Context, entity testOptionsModel) bool { return entity.Text != "locked" })
+
+	resource := FromModel[testOptionsModel]("entries", db, policy)
+	resource.CreateBindType(testBulkWrite{})
+	resource.WriteBindType(testBulkWrite{})
+	resource.BulkOptions(BulkOptions{BatchSize: 2, MaxItems: 5})
+
+	e := echo.New()
+	_, err := New(e, db, []Provider{&resource}, WithoutMigrations())
+	assert.NoError(t, err)
+
+	serve := func(method string, target string, body string) (int, BulkResponse) {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var response BulkResponse
+		_ = json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec.Code, response
+	}
+	count := func() int64 {
+		var count int64
+		assert.NoError(t, db.Model(&testOptionsModel{}).Count(&count).Error)
+		return count
+	}
+
+	code, response := serve(http.MethodPost, "/entries/bulk", `[{"Text":"a"},{"Text":"b"},{"Text":"locked"}]`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []BulkItemResult{
+		{Index: 0, ID: 1, Status: http.StatusOK},
+		{Index: 1, ID: 2, Status: http.StatusOK},
+		{Index: 2, ID: 3, Status: http.StatusOK},
+	}, response.Results)
+
+	code, _ = serve(http.MethodPost, "/entries/bulk", `[{},{},{},{},{},{}]`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// Atomic updates roll back every item when one is denied.
+	code, response = serve(http.MethodPatch, "/entries/bulk", `[{"id":1,"changes":{"Text":"changed"}},{"id":3,"changes":{"Text":"changed"}}]`)
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.True(t, response.RolledBack)
+	assert.Equal(t, http.StatusFailedDependency, response.Results[0].Status)
+	assert.Equal(t, http.StatusForbidden, response.Results[1].Status)
+
+	var first testOptionsModel
+	assert.NoError(t, db.First(&first, 1).Error)
+	assert.Equal(t, "a", first.Text)
+
+	// Partial updates apply the items which succeed.
+	code, response = serve(http.MethodPatch, "/entries/bulk?mode=partial", `[{"id":1,"changes":{"Text":"changed"}},{"id":3,"changes":{"Text":"changed"}},{"id":9,"changes":{}}]`)
+	assert.Equal(t, http.StatusMultiStatus, code)
+	assert.False(t, response.RolledBack)
+	assert.Equal(t, []int{http.StatusOK, http.StatusForbidden, http.StatusNotFound},
+		[]int{response.Results[0].Status, response.Results[1].Status, response.Results[2].Status})
+
+	assert.NoError(t, db.First(&first, 1).Error)
+	assert.Equal(t, "changed", first.Text)
+
+	code, response = serve(http.MethodDelete, "/entries?ids=1,3&mode=partial", "")
+	assert.Equal(t, http.StatusMultiStatus, code)
+	assert.Equal(t, http.StatusOK, response.Results[0].Status)
+	assert.Equal(t, int64(2), count())
+
+	code, _ = serve(http.MethodDelete, "/entries?ids=2,3", "")
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Equal(t, int64(2), count())
+
+	code, _ = serve(http.MethodDelete, "/entries?ids=x", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = serve(http.MethodDelete, "/entries?ids=2&mode=sometimes", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
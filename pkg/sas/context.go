@@ -0,0 +1,110 @@
+package sas
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Context is the request abstraction used by policies, queries and handlers, so they can be written once
+// for any framework. echo.Context satisfies it, and the sashttp package implements it for net/http.
+type Context interface {
+	Request() *http.Request
+
+	// Param returns a path parameter, such as the id of /entries/:id.
+	Param(name string) string
+	QueryParam(name string) string
+
+	// Bind decodes the request body into i.
+	Bind(i interface{}) error
+
+	// Get and Set store values for the duration of the request, such as from middlewares.
+	Get(key string) interface{}
+	Set(key string, val interface{})
+
+	JSON(code int, i interface{}) error
+	String(code int, s string) error
+	NoContent(code int) error
+}
+
+// ResponseWriterContext is implemented by contexts which expose their http.ResponseWriter directly.
+type ResponseWriterContext interface {
+	ResponseWriter() http.ResponseWriter
+}
+
+// HandlerFunc handles a request to a resource.
+type HandlerFunc func(c Context) error
+
+// MiddlewareFunc wraps a HandlerFunc, such as to check authentication.
+type MiddlewareFunc func(next HandlerFunc) HandlerFunc
+
+// Router is where resources add their routes. Paths use echo style parameters, such as /:id,
+// which adapters translate for their framework.
+type Router interface {
+	Add(method string, path string, handler HandlerFunc, middleware ...MiddlewareFunc)
+	Group(prefix string, middleware ...MiddlewareFunc) Router
+}
+
+// Chain wraps handler in middlewares, so the first middleware runs first.
+func Chain(handler HandlerFunc, middlewares ...MiddlewareFunc) HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
+
+// ResponseHeader returns the headers of the response, for contexts of echo and those implementing
+// ResponseWriterContext. It returns an empty header for other contexts, in which case changes are discarded.
+func ResponseHeader(c Context) http.Header {
+	if w := ResponseWriter(c); w != nil {
+		return w.Header()
+	}
+
+	return http.Header{}
+}
+
+// ResponseWriter returns the writer of the response of c, such as for streaming, or nil when c has none.
+func ResponseWriter(c Context) http.ResponseWriter {
+	switch c := c.(type) {
+	case echo.Context:
+		return c.Response()
+	case ResponseWriterContext:
+		return c.ResponseWriter()
+	}
+
+	return nil
+}
+
+// NewEchoRouter adapts an echo group to Router, for registering resources on echo without a Server.
+// The echo middlewares are added to every route.
+func NewEchoRouter(group *echo.Group, middlewares ...echo.MiddlewareFunc) Router {
+	return &echoRouter{
+		group:           group,
+		echoMiddlewares: middlewares,
+	}
+}
+
+type echoRouter struct {
+	group           *echo.Group
+	echoMiddlewares []echo.MiddlewareFunc
+	middlewares     []MiddlewareFunc
+}
+
+func (r *echoRouter) Add(method string, path string, handler HandlerFunc, middleware ...MiddlewareFunc) {
+	handler = Chain(handler, append(append([]MiddlewareFunc{}, r.middlewares...), middleware...)...)
+
+	r.group.Add(method, path, func(c echo.Context) error {
+		return handler(c)
+	}, r.echoMiddlewares...)
+}
+
+// Group creates a sub-router. The echo group is created without middlewares, as echo would otherwise
+// register catch-all routes for it.
+func (r *echoRouter) Group(prefix string, middleware ...MiddlewareFunc) Router {
+	return &echoRouter{
+		group:           r.group.Group(prefix),
+		echoMiddlewares: r.echoMiddlewares,
+		middlewares:     append(append([]MiddlewareFunc{}, r.middlewares...), middleware...),
+	}
+}
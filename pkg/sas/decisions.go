@@ -0,0 +1,241 @@
+package sas
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	patch "github.com/geraldo-labs/merge-struct"
+)
+
+// DefaultDecisionLogSize is the number of decisions a DecisionLog keeps when no size is given.
+const DefaultDecisionLogSize = 1000
+
+// Decision is the outcome of a policy predicate, with the reason for it and the rule which made it.
+type Decision struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+	Rule    string `json:"rule,omitempty"`
+}
+
+// Allowed is a decision allowing an operation for reason.
+func Allowed(reason string) Decision {
+	return Decision{Allowed: true, Reason: reason}
+}
+
+// Denied is a decision denying an operation for reason.
+func Denied(reason string) Decision {
+	return Decision{Reason: reason}
+}
+
+// ByRule sets the rule which made the decision.
+func (d Decision) ByRule(rule string) Decision {
+	d.Rule = rule
+	return d
+}
+
+func notSet(predicate string) Decision {
+	return Denied(predicate + " is not set").ByRule(predicate)
+}
+
+func predicateDecision(predicate string, allowed bool) Decision {
+	if allowed {
+		return Allowed("allowed by " + predicate).ByRule(predicate)
+	}
+
+	return Denied("denied by " + predicate).ByRule(predicate)
+}
+
+// DecisionError is returned for operations a policy denies. It is an ErrorResourceNoAccess, and its reason is
+// only written in the error response when echo runs in debug mode.
+type DecisionError struct {
+	Decision Decision
+}
+
+func (e *DecisionError) Error() string {
+	if e.Decision.Reason == "" {
+		return ErrorResourceNoAccess.Error()
+	}
+
+	return ErrorResourceNoAccess.Error() + ": " + e.Decision.Reason
+}
+
+func (e *DecisionError) Unwrap() error {
+	return ErrorResourceNoAccess
+}
+
+// DecisionOf returns the decision of a denied operation.
+func DecisionOf(err error) (Decision, bool) {
+	var decisionError *DecisionError
+	if !errors.As(err, &decisionError) {
+		return Decision{}, false
+	}
+
+	return decisionError.Decision, true
+}
+
+// DecisionRecord is a decision made for a request.
+type DecisionRecord struct {
+	Time      time.Time `json:"time"`
+	Principal string    `json:"principal,omitempty"`
+	Resource  string    `json:"resource"`
+	Operation Operation `json:"operation"`
+	EntityID  uint      `json:"entityId,omitempty"`
+
+	Decision
+}
+
+// DecisionLog keeps the latest decisions made by the policies of the resources in memory.
+type DecisionLog struct {
+	mu sync.Mutex
+
+	buffer []DecisionRecord
+	next   int
+	full   bool
+}
+
+// NewDecisionLog creates a log which keeps the latest size decisions.
+func NewDecisionLog(size int) *DecisionLog {
+	if size <= 0 {
+		size = DefaultDecisionLogSize
+	}
+
+	return &DecisionLog{
+		buffer: make([]DecisionRecord, size),
+	}
+}
+
+// Record adds record to the log, replacing the oldest record when it is full.
+func (l *DecisionLog) Record(record DecisionRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buffer[l.next] = record
+	l.next = (l.next + 1) % len(l.buffer)
+	l.full = l.full || l.next == 0
+}
+
+// Records returns the decisions in the log, newest first. A nil log has no decisions.
+func (l *DecisionLog) Records() []DecisionRecord {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := l.next
+	if l.full {
+		count = len(l.buffer)
+	}
+
+	records := make([]DecisionRecord, 0, count)
+	for i := 1; i <= count; i++ {
+		records = append(records, l.buffer[(l.next-i+len(l.buffer))%len(l.buffer)])
+	}
+
+	return records
+}
+
+// DecisionsEndpoint returns a read-only handler listing the decisions in log, newest first. The resource,
+// principal and outcome (allowed or denied) query parameters filter the decisions. A nil can denies every call.
+func DecisionsEndpoint(log *DecisionLog, can func(c Context) bool) HandlerFunc {
+	return func(c Context) error {
+		if can == nil || !can(c) {
+			return ErrorResourceNoAccess
+		}
+
+		resource := c.QueryParam("resource")
+		principal := c.QueryParam("principal")
+		outcome := c.QueryParam("outcome")
+		if outcome != "" && outcome != "allowed" && outcome != "denied" {
+			return errors.Join(ErrorResourceInvalidData, errors.New("outcome must be allowed or denied"))
+		}
+
+		records := []DecisionRecord{}
+		for _, record := range log.Records() {
+			if resource != "" && record.Resource != resource {
+				continue
+			}
+			if principal != "" && record.Principal != principal {
+				continue
+			}
+			if outcome != "" && record.Allowed != (outcome == "allowed") {
+				continue
+			}
+			records = append(records, record)
+		}
+
+		return c.JSON(http.StatusOK, records)
+	}
+}
+
+// ServeDecisions serves the decision log set with WithDecisionLog read-only at path under the base path, such as
+// /_sas/decisions. See DecisionsEndpoint.
+func (s *Server) ServeDecisions(path string, can func(c Context) bool) {
+	s.serveGet(path, DecisionsEndpoint(s.config.decisions, can))
+}
+
+// Decisions records the decisions of the policy of the resource in log.
+func (mr *ModelResource[T]) Decisions(log *DecisionLog) {
+	mr.decisions = log
+}
+
+// decisionsByDefault records the decisions of the resource in log, unless it has its own.
+func (mr *ModelResource[T]) decisionsByDefault(log *DecisionLog) {
+	if mr.decisions == nil {
+		mr.decisions = log
+	}
+}
+
+// authorizeEntity checks CanCreateEntity for creating entity, when it is set.
+func (mr *ModelResource[T]) authorizeEntity(c Context, operation Operation, entity *T) error {
+	if mr.Policy.createEntity == nil {
+		return nil
+	}
+
+	return mr.authorize(c, operation, entity, mr.Policy.createEntity(c, *entity))
+}
+
+// authorizeChange checks CanWriteChange for writing bound to entity, when it is set. The changes are applied to a
+// copy of entity, as the default write query applies them.
+func (mr *ModelResource[T]) authorizeChange(c Context, operation Operation, entity *T, bound any) error {
+	if mr.Policy.writeChange == nil {
+		return nil
+	}
+
+	changed := *entity
+	if _, err := patch.Struct(&changed, bound); err != nil {
+		return errors.Join(ErrorResourceInvalidData, err)
+	}
+
+	return mr.authorize(c, operation, entity, mr.Policy.writeChange(c, *entity, changed))
+}
+
+// authorize records decision for operation on entity, which is nil for operations without one, and returns a
+// DecisionError when it denies the operation.
+func (mr *ModelResource[T]) authorize(c Context, operation Operation, entity *T, decision Decision) error {
+	if mr.decisions != nil {
+		record := DecisionRecord{
+			Time:      time.Now().UTC(),
+			Resource:  mr.Name,
+			Operation: operation,
+			Decision:  decision,
+		}
+		if entity != nil {
+			record.EntityID = entityID(entity)
+		}
+		if principal := PrincipalFrom(c); principal != nil {
+			record.Principal = principal.ID
+		}
+
+		mr.decisions.Record(record)
+	}
+
+	if !decision.Allowed {
+		return &DecisionError{Decision: decision}
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package sas
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecisions(t *testing.T) {
+	db, resource := newTestEntries(t, endpoints.AllEndpoints)
+
+	rules := NewRules[testOptionsModel]()
+	rules.Allow(Read | Create).ForRoles("editor").Named("editors")
+	rules.Deny(Delete).Always().Named("no deletes")
+
+	resource.Policy = rules.Policy(endpoints.AllEndpoints)
+
+	log := NewDecisionLog(3)
+	s := newTestServer(t, db, []Provider{&resource},
+		WithoutMigrations(),
+		WithDecisionLog(log),
+		WithAuthenticators(StaticTokens(map[string]*Principal{"editor": {ID: "alice", Roles: []string{"editor"}}})),
+	)
+	s.ServeDecisions("/_sas/decisions", func(c Context) bool { return true })
+
+	serve := func(method string, target string, token string) *httptest.ResponseRecorder {
+		return s.serve(method, target, `{"Text":"a"}`, "Authorization", "Bearer "+token)
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/entries", "editor").Code)
+
+	rec := serve(http.MethodGet, "/entries", "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, ErrorResourceNoAccess.Error(), rec.Body.String())
+
+	// The reason is only written in debug mode.
+	s.e.Debug = true
+	rec = serve(http.MethodDelete, "/entries/1", "editor")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "denied by rule no deletes")
+
+	rec = serve(http.MethodGet, "/_sas/decisions?outcome=denied", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var records []DecisionRecord
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &records))
+	assert.Len(t, records, 2)
+	assert.Equal(t, OperationDelete, records[0].Operation)
+	assert.Equal(t, "alice", records[0].Principal)
+	assert.Equal(t, uint(1), records[0].EntityID)
+	assert.Equal(t, "no deletes", records[0].Rule)
+	assert.Equal(t, OperationList, records[1].Operation)
+	assert.Equal(t, "no rule allows readAll", records[1].Reason)
+
+	rec = serve(http.MethodGet, "/_sas/decisions?outcome=allowed&principal=alice", "")
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &records))
+	assert.Len(t, records, 1)
+	assert.Equal(t, OperationCreate, records[0].Operation)
+	assert.Equal(t, "editors", records[0].Rule)
+
+	// The log only keeps the latest decisions.
+	serve(http.MethodGet, "/entries", "editor")
+	assert.Len(t, log.Records(), 3)
+	assert.Equal(t, OperationList, log.Records()[0].Operation)
+	assert.True(t, log.Records()[0].Allowed)
+}
+
+func TestDecisionOf(t *testing.T) {
+	policy := NewPolicy[testPolicyModel](endpoints.AllEndpoints)
+	decision := policy.listAll(echo.New().NewContext(nil, nil))
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "CanListAll is not set", decision.Reason)
+
+	err := &DecisionError{Decision: decision}
+	assert.ErrorIs(t, err, ErrorResourceNoAccess)
+
+	got, ok := DecisionOf(err)
+	assert.True(t, ok)
+	assert.Equal(t, decision, got)
+
+	_, ok = DecisionOf(ErrorResourceNoAccess)
+	assert.False(t, ok)
+}
@@ -0,0 +1,145 @@
+package sas
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+)
+
+// Operation identifies what a route does with a resource.
+type Operation string
+
+const (
+	OperationList   Operation = "list"
+	OperationGet    Operation = "get"
+	OperationCreate Operation = "create"
+	OperationWrite  Operation = "write"
+	OperationDelete Operation = "delete"
+
+	OperationBulkCreate Operation = "bulkCreate"
+	OperationBulkWrite  Operation = "bulkWrite"
+	OperationBulkDelete Operation = "bulkDelete"
+
+	OperationHistory        Operation = "history"
+	OperationHistoryVersion Operation = "historyVersion"
+	OperationRevert         Operation = "revert"
+
+	OperationEvents          Operation = "events"
+	OperationEventsWebSocket Operation = "eventsWebSocket"
+
+	// OperationAction is a custom action added with Action or ItemAction.
+	OperationAction Operation = "action"
+)
+
+// Describer is implemented by resources which can describe their routes and types, such as ModelResource.
+type Describer interface {
+	Describe() ResourceDescription
+}
+
+// ResourceDescription describes what a resource exposes.
+type ResourceDescription struct {
+	Name      string
+	Endpoints endpoints.HttpEndpointType
+
+	// Version is the name of the Version the resource is part of, if any.
+	Version    string
+	Deprecated bool
+
+	Routes []RouteDescription
+
+	Model          reflect.Type
+	ReadType       reflect.Type
+	CreateBindType reflect.Type
+	WriteBindType  reflect.Type
+
+	// Predicates reports which policy predicates are customized, and which deny by default.
+	Predicates []PredicateDescription
+
+	Docs ResourceDocs
+}
+
+// RouteDescription describes a single enabled route of a resource.
+// Path is the full echo path, such as /entries/:id.
+type RouteDescription struct {
+	Operation Operation
+	Method    string
+	Path      string
+
+	// Action is the name of a custom action, and RequestType the type its request body is bound to.
+	Action      string
+	RequestType reflect.Type
+}
+
+// ResourceDocs overrides the generated documentation of a resource.
+type ResourceDocs struct {
+	Summary     string
+	Description string
+	Tags        []string
+
+	// Operations overrides the summary of individual operations.
+	Operations map[Operation]string
+}
+
+// resourceRoute is a route a resource can serve. Routes without an endpoint, such as actions, are always enabled.
+type resourceRoute struct {
+	operation Operation
+	endpoint  endpoints.HttpEndpointType
+	method    string
+	path      string
+	handler   HandlerFunc
+
+	action      string
+	requestType reflect.Type
+}
+
+// Describe describes every resource which implements Describer, including those of versions.
+// The paths of the routes are relative to the base path of the server.
+func Describe(resources []Provider) []ResourceDescription {
+	return describeResources(resources, "")
+}
+
+// Describe returns the enabled routes and the types used by the resource.
+func (mr *ModelResource[T]) Describe() ResourceDescription {
+	description := ResourceDescription{
+		Name:      mr.Name,
+		Endpoints: mr.Policy.EnabledEndpoints,
+		Model:     reflect.TypeOf((*T)(nil)).Elem(),
+		Docs:      mr.docs,
+
+		Predicates: mr.Policy.Predicates(),
+	}
+
+	if mr.readType != nil {
+		description.ReadType = reflect.TypeOf(mr.readType)
+	}
+	if mr.createBindType != nil {
+		description.CreateBindType = reflect.TypeOf(mr.createBindType)
+	}
+	if mr.writeBindType != nil {
+		description.WriteBindType = reflect.TypeOf(mr.writeBindType)
+	}
+
+	base := "/" + strings.Trim(mr.Name, "/")
+	for _, route := range mr.routes() {
+		if !endpoints.Has(mr.Policy.EnabledEndpoints, route.endpoint) {
+			continue
+		}
+
+		description.Routes = append(description.Routes, RouteDescription{
+			Operation: route.operation,
+			Method:    route.method,
+			Path:      base + route.path,
+
+			Action:      route.action,
+			RequestType: route.requestType,
+		})
+	}
+
+	return description
+}
+
+// Docs sets the summary, description and tags used when documenting the resource.
+func (mr *ModelResource[T]) Docs(docs ResourceDocs) {
+	mr.docs = docs
+}
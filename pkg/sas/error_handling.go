@@ -3,7 +3,6 @@ package sas
 import (
 	"errors"
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/gommon/log"
 	"net/http"
 )
 
@@ -13,42 +12,79 @@ var (
 	ErrorResourceNotFound    = errors.New("resource requested does not exist")
 	ErrorResourceInvalidID   = errors.New("id specified is not valid")
 	ErrorResourceInvalidData = errors.New("invalid data for the requested operation")
+	ErrorResourceConflict    = errors.New("resource was changed by another request")
 
 	ErrorDatabaseIssue        = errors.New("database issue")
 	ErrorFatalSetupNoBindType = errors.New("no bind type has been set for this operation")
 )
 
+// ManagedModelErrorHandler writes the sas errors with a matching status code, and logs with the global logger of gommon.
 func ManagedModelErrorHandler(err error, c echo.Context) {
-	code := http.StatusInternalServerError
-	message := "unknown"
-
-	switch {
-	case errors.Is(err, ErrorResourceNoAccess):
-		code = http.StatusForbidden
-		message = ErrorResourceNoAccess.Error()
-
-	case errors.Is(err, ErrorResourceNotFound):
-		code = http.StatusNotFound
-		message = ErrorResourceNotFound.Error()
-
-	case errors.Is(err, ErrorResourceInvalidID):
-		code = http.StatusBadRequest
-		message = ErrorResourceInvalidID.Error()
-
-	case errors.Is(err, ErrorResourceInvalidData):
-		code = http.StatusBadRequest
-		message = ErrorResourceInvalidData.Error()
-
-	case errors.Is(err, ErrorDatabaseIssue):
-		code = http.StatusInternalServerError
-		message = ErrorDatabaseIssue.Error()
-
-	default:
-		code = http.StatusInternalServerError
-		message = "unknown"
-		log.Errorf("Unhandled error type: %s", err)
+	handleError(gommonLogger{}, err, c)
+}
+
+// NewErrorHandler returns the error handler New installs by default, which behaves as ManagedModelErrorHandler
+// but logs to logger.
+func NewErrorHandler(logger Logger) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		handleError(logger, err, c)
+	}
+}
+
+func handleError(logger Logger, err error, c echo.Context) {
+	code, message, ok := ErrorStatus(err)
+
+	var httpError *echo.HTTPError
+	if !ok {
+		if errors.As(err, &httpError) {
+			// Errors from echo itself, such as for unknown routes, keep their status code.
+			code = httpError.Code
+			message = http.StatusText(code)
+			if text, ok := httpError.Message.(string); ok {
+				message = text
+			}
+		} else {
+			logger.Errorf("Unhandled error type: %s", err)
+		}
+	}
+
+	// The reason a policy denied the request helps debugging, but may tell callers too much in production.
+	if decision, ok := DecisionOf(err); ok && decision.Reason != "" && c.Echo() != nil && c.Echo().Debug {
+		message += ": " + decision.Reason
 	}
 
-	log.Warn("Handled error: ", err)
+	logger.Warnf("Handled error: %s", err)
 	_ = c.String(code, message)
 }
+
+// ErrorStatusCode pairs a sas error with the status code it is written with.
+type ErrorStatusCode struct {
+	Err  error
+	Code int
+}
+
+// ErrorStatusCodes are the sas errors ErrorStatus matches, in the order they are matched.
+// Clients can use it to map the message of a response back to the error.
+var ErrorStatusCodes = []ErrorStatusCode{
+	{ErrorUnauthenticated, http.StatusUnauthorized},
+	{ErrorResourceNoAccess, http.StatusForbidden},
+	{ErrorResourceNotFound, http.StatusNotFound},
+	{ErrorResourceInvalidID, http.StatusBadRequest},
+	{ErrorResourceInvalidData, http.StatusBadRequest},
+	{ErrorUnknownVersion, http.StatusBadRequest},
+	{ErrorTenantUnresolved, http.StatusBadRequest},
+	{ErrorResourceConflict, http.StatusConflict},
+	{ErrorDatabaseIssue, http.StatusInternalServerError},
+}
+
+// ErrorStatus returns the status code and message which are written for a sas error.
+// For any other error it returns status 500 and false, so adapters can handle their own errors.
+func ErrorStatus(err error) (int, string, bool) {
+	for _, status := range ErrorStatusCodes {
+		if errors.Is(err, status.Err) {
+			return status.Code, status.Err.Error(), true
+		}
+	}
+
+	return http.StatusInternalServerError, "unknown", false
+}
@@ -0,0 +1,442 @@
+package sas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	contextKeyPendingEvents = "sas.pendingEvents"
+
+	// DefaultEventBufferSize is the number of events kept for resuming by NewBroker, when no size is given.
+	DefaultEventBufferSize = 1024
+
+	// eventSubscriberBuffer is the number of events a subscriber can fall behind before it is disconnected.
+	eventSubscriberBuffer = 64
+
+	eventKeepAlive = 30 * time.Second
+)
+
+// ErrorStreamingUnsupported is returned by the event endpoints when the response cannot be streamed.
+var ErrorStreamingUnsupported = errors.New("response does not support streaming")
+
+// EventType is the kind of change an Event reports.
+type EventType string
+
+const (
+	EventCreated EventType = "created"
+	EventUpdated EventType = "updated"
+	EventDeleted EventType = "deleted"
+)
+
+// Event is a change to an entity of a resource. Data holds the entity after the change, as returned by the get
+// endpoint of the resource, or the entity before the change for deletes.
+type Event struct {
+	// ID is assigned by the broker when the event is published. Webhook deliveries are logged before the change is
+	// committed, so their events have no ID, and are told apart by the X-Sas-Delivery header instead.
+	ID       uint64    `json:"id"`
+	Resource string    `json:"resource"`
+	Type     EventType `json:"type"`
+	EntityID uint      `json:"entityId"`
+	// Tenant is the tenant of the request which made the change, for resources with tenancy.
+	Tenant string `json:"tenant,omitempty"`
+
+	Data json.RawMessage `json:"data"`
+
+	Timestamp time.Time `json:"timestamp"`
+
+	// entity is the model the event was created from, which the policy of a subscriber is checked against.
+	entity any
+}
+
+// Broker delivers the events of resources to the connections subscribed to them, in process.
+// The latest events are kept in a ring buffer, so connections can resume after the last event they received.
+type Broker struct {
+	mu sync.Mutex
+
+	lastID uint64
+	buffer []Event
+	next   int
+	full   bool
+
+	subscribers map[*Subscription]struct{}
+
+	origins []string
+}
+
+// Subscription receives the events of a single resource.
+type Subscription struct {
+	broker   *Broker
+	resource string
+	events   chan Event
+
+	// Replay holds the buffered events after the ID the subscription resumed from.
+	Replay []Event
+}
+
+// NewBroker creates a broker which keeps the latest size events for resuming.
+func NewBroker(size int) *Broker {
+	if size <= 0 {
+		size = DefaultEventBufferSize
+	}
+
+	return &Broker{
+		buffer:      make([]Event, size),
+		subscribers: map[*Subscription]struct{}{},
+	}
+}
+
+// AllowOrigins allows WebSocket connections from pages of origins, such as https://app.example.com.
+// By default only pages served from the host of the request may connect.
+func (b *Broker) AllowOrigins(origins ...string) *Broker {
+	b.origins = append(b.origins, origins...)
+	return b
+}
+
+// checkOrigin rejects WebSocket connections from pages of other origins, so other sites cannot read the events
+// with the credentials of their visitors. Clients which are not browsers send no origin, and are accepted.
+func (b *Broker) checkOrigin(config *websocket.Config, req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" || slices.Contains(b.origins, origin) {
+		return nil
+	}
+
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Host != req.Host {
+		return fmt.Errorf("origin %q is not allowed", origin)
+	}
+
+	return nil
+}
+
+// Publish assigns the next ID to event, buffers it and delivers it to the subscribers of its resource.
+// Subscribers which have fallen too far behind are disconnected instead of blocking, and can resume from
+// the last event they received.
+func (b *Broker) Publish(event Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+
+	b.buffer[b.next] = event
+	b.next = (b.next + 1) % len(b.buffer)
+	b.full = b.full || b.next == 0
+
+	for subscription := range b.subscribers {
+		if subscription.resource != event.Resource {
+			continue
+		}
+
+		select {
+		case subscription.events <- event:
+		default:
+			delete(b.subscribers, subscription)
+			close(subscription.events)
+		}
+	}
+
+	return event
+}
+
+// Subscribe subscribes to the events of resource. When lastEventID is not 0, the buffered events after it
+// are returned in Replay. Events older than the buffer are lost.
+func (b *Broker) Subscribe(resource string, lastEventID uint64) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subscription := &Subscription{
+		broker:   b,
+		resource: resource,
+		events:   make(chan Event, eventSubscriberBuffer),
+	}
+	b.subscribers[subscription] = struct{}{}
+
+	if lastEventID == 0 {
+		return subscription
+	}
+
+	start := 0
+	if b.full {
+		start = b.next
+	}
+	for i := 0; i < len(b.buffer); i++ {
+		event := b.buffer[(start+i)%len(b.buffer)]
+		if event.ID > lastEventID && event.Resource == resource {
+			subscription.Replay = append(subscription.Replay, event)
+		}
+	}
+
+	return subscription
+}
+
+// Events returns the channel of new events, which is closed when the subscription is closed or disconnected.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close stops the subscription.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	if _, ok := s.broker.subscribers[s]; ok {
+		delete(s.broker.subscribers, s)
+		close(s.events)
+	}
+}
+
+// Events publishes the creates, writes and deletes of the resource to broker, and adds GET /_events, which streams
+// them as Server-Sent Events, and GET /_events/ws, which streams them as JSON messages over a WebSocket.
+// Subscribing requires CanListAll, and each event is only sent to connections CanListById allows for the entity.
+// Connections resume with the Last-Event-ID header, or the lastEventId query parameter.
+func (mr *ModelResource[T]) Events(broker *Broker) {
+	mr.events = broker
+}
+
+func (mr *ModelResource[T]) eventRoutes() []resourceRoute {
+	if mr.events == nil {
+		return nil
+	}
+
+	return []resourceRoute{
+		{operation: OperationEvents, method: http.MethodGet, path: "/_events", handler: mr.streamEvents},
+		{operation: OperationEventsWebSocket, method: http.MethodGet, path: "/_events/ws", handler: mr.streamEventsWebSocket},
+	}
+}
+
+func (mr *ModelResource[T]) streamEvents(c Context) error {
+	if err := mr.authorize(c, OperationEvents, nil, mr.Policy.listAll(c)); err != nil {
+		return err
+	}
+
+	w := ResponseWriter(c)
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return ErrorStreamingUnsupported
+	}
+
+	lastEventID, err := eventsLastID(c)
+	if err != nil {
+		return err
+	}
+
+	subscription := mr.events.Subscribe(mr.Name, lastEventID)
+	defer subscription.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return mr.deliverEvents(c, subscription, c.Request().Context().Done(), func(event *Event) error {
+		if event == nil {
+			_, err := fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+			return err
+		}
+
+		_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+		flusher.Flush()
+		return err
+	})
+}
+
+func (mr *ModelResource[T]) streamEventsWebSocket(c Context) error {
+	if err := mr.authorize(c, OperationEventsWebSocket, nil, mr.Policy.listAll(c)); err != nil {
+		return err
+	}
+
+	w := ResponseWriter(c)
+	if w == nil {
+		return ErrorStreamingUnsupported
+	}
+
+	lastEventID, err := eventsLastID(c)
+	if err != nil {
+		return err
+	}
+
+	server := websocket.Server{Handshake: mr.events.checkOrigin}
+	server.Handler = func(ws *websocket.Conn) {
+		subscription := mr.events.Subscribe(mr.Name, lastEventID)
+		defer subscription.Close()
+
+		// Messages from the client are ignored, and only read to notice when the connection closes.
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+
+			var message []byte
+			for websocket.Message.Receive(ws, &message) == nil {
+			}
+		}()
+
+		_ = mr.deliverEvents(c, subscription, closed, func(event *Event) error {
+			if event == nil {
+				return nil
+			}
+
+			return websocket.JSON.Send(ws, event)
+		})
+	}
+	server.ServeHTTP(w, c.Request())
+
+	return nil
+}
+
+// deliverEvents sends the replayed and new events of the tenant of the connection it may list, until done is
+// closed or the subscription ends. send is called with nil to keep the connection alive.
+func (mr *ModelResource[T]) deliverEvents(c Context, subscription *Subscription, done <-chan struct{}, send func(event *Event) error) error {
+	tenant := Tenant(c)
+	allowed := func(event Event) bool {
+		entity, ok := event.entity.(*T)
+		return ok && event.Tenant == tenant && mr.Policy.canListById(c, *entity)
+	}
+
+	for _, event := range subscription.Replay {
+		if !allowed(event) {
+			continue
+		}
+		if err := send(&event); err != nil {
+			return err
+		}
+	}
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-done:
+			return nil
+
+		case <-keepAlive.C:
+			if err := send(nil); err != nil {
+				return err
+			}
+
+		case event, ok := <-subscription.Events():
+			if !ok {
+				return nil
+			}
+			if !allowed(event) {
+				continue
+			}
+			if err := send(&event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// publish publishes a change to the broker of the resource. Changes of bulk requests are held back until their
+// transaction is committed, see publishPending.
+func (mr *ModelResource[T]) publish(c Context, action Operation, before *T, after *T) {
+	if mr.events == nil {
+		return
+	}
+
+	event, ok := mr.newEvent(c, action, before, after)
+	if !ok {
+		return
+	}
+
+	if pending, ok := c.Get(contextKeyPendingEvents).(*[]Event); ok && pending != nil {
+		*pending = append(*pending, event)
+		return
+	}
+
+	mr.dispatch(event)
+}
+
+// newEvent creates the event of a change made by the request c, without an ID. It reports false when the change
+// has no entity, or the entity cannot be encoded.
+func (mr *ModelResource[T]) newEvent(c Context, action Operation, before *T, after *T) (Event, bool) {
+	event := Event{Resource: mr.Name, Tenant: Tenant(c), Timestamp: time.Now().UTC()}
+	entity := after
+	switch action {
+	case OperationCreate:
+		event.Type = EventCreated
+	case OperationDelete:
+		event.Type = EventDeleted
+		entity = before
+	default:
+		event.Type = EventUpdated
+	}
+	if entity == nil {
+		return Event{}, false
+	}
+
+	// The entity is copied, as writes change it in place after the event is created.
+	copied := *entity
+	event.entity = &copied
+	event.EntityID = entityID(entity)
+
+	var data any = entity
+	if mr.readTransform != nil {
+		data = mr.readTransform(*entity)
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, false
+	}
+	event.Data = encoded
+
+	return event, true
+}
+
+// dispatch publishes event to the broker of the resource.
+func (mr *ModelResource[T]) dispatch(event Event) {
+	if mr.events != nil {
+		mr.events.Publish(event)
+	}
+}
+
+// holdEvents holds back the events published for c until publishPending is called.
+func holdEvents(c Context) {
+	c.Set(contextKeyPendingEvents, &[]Event{})
+}
+
+// publishPending publishes the held back events of c when commit is true, and discards them otherwise.
+func (mr *ModelResource[T]) publishPending(c Context, commit bool) {
+	pending, _ := c.Get(contextKeyPendingEvents).(*[]Event)
+	c.Set(contextKeyPendingEvents, nil)
+
+	if pending == nil || !commit {
+		return
+	}
+
+	for _, event := range *pending {
+		mr.dispatch(event)
+	}
+}
+
+// eventsLastID reads the ID of the last event a connection received, from the Last-Event-ID header sent by
+// EventSource, or the lastEventId query parameter.
+func eventsLastID(c Context) (uint64, error) {
+	value := c.Request().Header.Get("Last-Event-ID")
+	if value == "" {
+		value = c.QueryParam("lastEventId")
+	}
+	if value == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, errors.Join(ErrorResourceInvalidID, err)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,129 @@
+package sas
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+)
+
+func TestBrokerReplay(t *testing.T) {
+	broker := NewBroker(2)
+	for i := 0; i < 3; i++ {
+		broker.Publish(Event{Resource: "entries"})
+	}
+	broker.Publish(Event{Resource: "other"})
+
+	subscription := broker.Subscribe("entries", 1)
+	defer subscription.Close()
+	assert.Len(t, subscription.Replay, 1)
+	assert.Equal(t, uint64(3), subscription.Replay[0].ID)
+
+	assert.Empty(t, broker.Subscribe("entries", 0).Replay)
+
+	// Subscribers which fall behind are disconnected instead of blocking the publisher.
+	for i := 0; i <= eventSubscriberBuffer; i++ {
+		broker.Publish(Event{Resource: "entries"})
+	}
+	received := 0
+	for range subscription.Events() {
+		received++
+	}
+	assert.Equal(t, eventSubscriberBuffer, received)
+}
+
+func TestEvents(t *testing.T) {
+	db := newTestOptionsDB(t)
+	assert.NoError(t, db.AutoMigrate(&testOptionsModel{}))
+
+	policy := NewPolicy[testOptionsModel](endpoints.AllEndpoints)
+	policy.
+		CanListAll(func(c Context) bool { return true }).
+		CanCreate(func(c Context) bool { return true }).
+		CanListById(func(c Context, entity testOptionsModel) bool { return entity.Text != "secret" })
+
+	resource := FromModel[testOptionsModel]("entries", db, policy)
+	resource.CreateBindType(testBulkWrite{})
+	resource.Events(NewBroker(16).AllowOrigins("https://app.example.com"))
+
+	e := echo.New()
+	_, err := New(e, db, []Provider{&resource}, WithoutMigrations())
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+
+	create := func(text string) {
+		resp, err := http.Post(server.URL+"/entries", "application/json", strings.NewReader(`{"Text":"`+text+`"}`))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		_ = resp.Body.Close()
+	}
+
+	subscribe := func(lastEventID string) *bufio.Reader {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		t.Cleanup(cancel)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/entries/_events", nil)
+		assert.NoError(t, err)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		t.Cleanup(func() { _ = resp.Body.Close() })
+
+		return bufio.NewReader(resp.Body)
+	}
+
+	readEvent := func(r *bufio.Reader) string {
+		var lines []string
+		for {
+			line, err := r.ReadString('\n')
+			assert.NoError(t, err)
+			if line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	stream := subscribe("")
+	create("secret")
+	create("visible")
+
+	event := readEvent(stream)
+	assert.Contains(t, event, "id: 2\nevent: created\n")
+	assert.Contains(t, event, `"Text":"visible"`)
+
+	create("resumed")
+	assert.Contains(t, readEvent(subscribe("2")), `"Text":"resumed"`)
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/entries/_events/ws?lastEventId=1"
+	_, err = websocket.Dial(wsURL, "", "https://evil.example.com")
+	assert.Error(t, err)
+
+	allowed, err := websocket.Dial(wsURL, "", "https://app.example.com")
+	assert.NoError(t, err)
+	allowed.Close()
+
+	ws, err := websocket.Dial(wsURL, "", server.URL)
+	assert.NoError(t, err)
+	defer ws.Close()
+
+	var received Event
+	assert.NoError(t, websocket.JSON.Receive(ws, &received))
+	assert.Equal(t, uint64(2), received.ID)
+	assert.Equal(t, EventCreated, received.Type)
+	assert.Contains(t, string(received.Data), `"Text":"visible"`)
+}
@@ -0,0 +1,45 @@
+package sas
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/imthatgin/sas/pkg/openapi"
+)
+
+//go:embed explorer
+var explorerFiles embed.FS
+
+// ServeExplorer serves an interactive API explorer for the registered resources at path, under the base path.
+// The explorer is self-contained, and reads its own copy of the OpenAPI document from path/openapi.json.
+func (s *Server) ServeExplorer(path string, info openapi.Info) error {
+	path = "/" + strings.Trim(path, "/")
+
+	assets, err := fs.Sub(explorerFiles, "explorer")
+	if err != nil {
+		return err
+	}
+	fileServer := http.StripPrefix(s.config.basePath+path+"/", http.FileServer(http.FS(assets)))
+
+	// The explorer uses relative URLs, so it must be served from a path ending in a slash.
+	s.serveGet(path, func(c Context) error {
+		ResponseHeader(c).Set("Location", s.config.basePath+path+"/")
+		return c.NoContent(http.StatusMovedPermanently)
+	})
+	s.serveGet(path+"/openapi.json", func(c Context) error {
+		return c.JSON(http.StatusOK, s.OpenAPI(info))
+	})
+	s.serveGet(path+"/*", func(c Context) error {
+		w := ResponseWriter(c)
+		if w == nil {
+			return ErrorStreamingUnsupported
+		}
+
+		fileServer.ServeHTTP(w, c.Request())
+		return nil
+	})
+
+	return nil
+}
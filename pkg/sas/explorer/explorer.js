@@ -0,0 +1,168 @@
+(function () {
+	"use strict";
+
+	const methods = ["get", "post", "put", "patch", "delete"];
+	const authName = document.getElementById("auth-name");
+	const authValue = document.getElementById("auth-value");
+
+	// The auth header is remembered between visits, but never leaves the browser other than in requests.
+	authName.value = localStorage.getItem("sas-explorer-auth-name") || authName.value;
+	authValue.value = localStorage.getItem("sas-explorer-auth-value") || "";
+	authName.addEventListener("change", () => localStorage.setItem("sas-explorer-auth-name", authName.value));
+	authValue.addEventListener("change", () => localStorage.setItem("sas-explorer-auth-value", authValue.value));
+
+	function element(tag, attributes, ...children) {
+		const node = document.createElement(tag);
+		Object.entries(attributes || {}).forEach(([key, value]) => {
+			if (key === "class") {
+				node.className = value;
+			} else {
+				node.setAttribute(key, value);
+			}
+		});
+		children.forEach((child) => node.append(child));
+		return node;
+	}
+
+	function resolve(spec, schema) {
+		if (schema && schema.$ref) {
+			return resolve(spec, spec.components.schemas[schema.$ref.split("/").pop()]);
+		}
+		return schema || {};
+	}
+
+	// example builds a sample value for a schema, used to prefill request bodies.
+	function example(spec, schema, depth) {
+		schema = resolve(spec, schema);
+		if (depth > 4) {
+			return null;
+		}
+		if (schema.anyOf) {
+			return example(spec, schema.anyOf[0], depth + 1);
+		}
+
+		const type = Array.isArray(schema.type) ? schema.type[0] : schema.type;
+		switch (type) {
+			case "object": {
+				const value = {};
+				Object.entries(schema.properties || {}).forEach(([name, property]) => {
+					value[name] = example(spec, property, depth + 1);
+				});
+				return value;
+			}
+			case "array":
+				return [example(spec, schema.items, depth + 1)];
+			case "integer":
+			case "number":
+				return 0;
+			case "boolean":
+				return false;
+			case "string":
+				return schema.format === "date-time" ? new Date().toISOString() : "";
+			default:
+				return null;
+		}
+	}
+
+	function renderOperation(spec, path, method, operation) {
+		const form = element("form");
+		const parameters = (operation.parameters || []).filter((parameter) => parameter.in === "path");
+
+		const inputs = {};
+		parameters.forEach((parameter) => {
+			inputs[parameter.name] = element("input", { name: parameter.name, required: "" });
+			form.append(element("label", {}, parameter.name + " ", inputs[parameter.name]));
+		});
+
+		let body = null;
+		if (operation.requestBody) {
+			const schema = operation.requestBody.content["application/json"].schema;
+			body = element("textarea", { spellcheck: "false" });
+			body.value = JSON.stringify(example(spec, schema, 0), null, 2);
+			form.append(element("label", {}, "Body", body));
+		}
+
+		const output = element("pre", { class: "response", hidden: "" });
+		form.append(element("button", { type: "submit" }, "Send"), output);
+
+		form.addEventListener("submit", async (event) => {
+			event.preventDefault();
+
+			let url = path;
+			parameters.forEach((parameter) => {
+				url = url.replace("{" + parameter.name + "}", encodeURIComponent(inputs[parameter.name].value));
+			});
+
+			const headers = {};
+			if (authName.value && authValue.value) {
+				headers[authName.value] = authValue.value;
+			}
+			if (body) {
+				headers["Content-Type"] = "application/json";
+			}
+
+			output.hidden = false;
+			output.textContent = method.toUpperCase() + " " + url + " ...";
+
+			try {
+				const response = await fetch(url, { method: method.toUpperCase(), headers: headers, body: body ? body.value : undefined });
+				let text = await response.text();
+				try {
+					text = JSON.stringify(JSON.parse(text), null, 2);
+				} catch (e) {
+					// Not JSON, so it is shown as is.
+				}
+				output.textContent = response.status + " " + response.statusText + "\n\n" + text;
+			} catch (error) {
+				output.textContent = "Request failed: " + error;
+			}
+		});
+
+		return element("details", { class: "operation" },
+			element("summary", {},
+				element("span", { class: "method " + method }, method.toUpperCase()),
+				element("span", { class: "path" }, path),
+				element("span", { class: "summary" }, operation.summary || "")),
+			form);
+	}
+
+	function render(spec) {
+		document.getElementById("title").textContent = spec.info.title + " " + spec.info.version;
+		document.title = spec.info.title + " API explorer";
+
+		const sections = {};
+		const container = document.getElementById("resources");
+		container.replaceChildren();
+
+		(spec.tags || []).forEach((tag) => {
+			sections[tag.name] = element("section", { class: "resource" }, element("h2", {}, tag.name));
+			if (tag.description) {
+				sections[tag.name].append(element("p", {}, tag.description));
+			}
+			container.append(sections[tag.name]);
+		});
+
+		Object.keys(spec.paths).sort().forEach((path) => {
+			methods.forEach((method) => {
+				const operation = spec.paths[path][method];
+				if (!operation) {
+					return;
+				}
+
+				const tag = (operation.tags || ["default"])[0];
+				if (!sections[tag]) {
+					sections[tag] = element("section", { class: "resource" }, element("h2", {}, tag));
+					container.append(sections[tag]);
+				}
+				sections[tag].append(renderOperation(spec, path, method, operation));
+			});
+		});
+	}
+
+	fetch("openapi.json")
+		.then((response) => response.json())
+		.then(render)
+		.catch((error) => {
+			document.getElementById("resources").textContent = "Could not load the API description: " + error;
+		});
+})();
@@ -0,0 +1,45 @@
+package sas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/imthatgin/sas/pkg/openapi"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_ServeExplorer(t *testing.T) {
+	resource := FromModel[testOpenAPIModel]("posts", nil, NewPolicy[testOpenAPIModel](endpoints.AllEndpoints))
+
+	e := echo.New()
+	s, err := New(e, nil, []Provider{&resource}, WithoutMigrations(), WithBasePath("/api"))
+	assert.NoError(t, err)
+	assert.NoError(t, s.ServeExplorer("/_sas/explorer", openapi.Info{Title: "test", Version: "1"}))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/_sas/explorer")
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, "/api/_sas/explorer/", rec.Header().Get(echo.HeaderLocation))
+
+	rec = get("/api/_sas/explorer/")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<script src="explorer.js">`)
+
+	rec = get("/api/_sas/explorer/explorer.js")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = get("/api/_sas/explorer/openapi.json")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"/api/posts/{id}"`)
+
+	rec = get("/_sas/explorer/")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
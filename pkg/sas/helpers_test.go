@@ -0,0 +1,87 @@
+package sas
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type testOptionsModel struct {
+	DefaultModel
+
+	Text string
+}
+
+type testBulkWrite struct {
+	Text string
+}
+
+func newTestOptionsDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	assert.NoError(t, err)
+
+	// Every connection to :memory: is a separate database, so only one may be used.
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	return db
+}
+
+// newTestEntries creates the entries resource of testOptionsModel on a new database. Its policy allows every
+// operation, and entities are created and written from testBulkWrite.
+func newTestEntries(t *testing.T, enabled endpoints.HttpEndpointType) (*gorm.DB, ModelResource[testOptionsModel]) {
+	db := newTestOptionsDB(t)
+	assert.NoError(t, db.AutoMigrate(&testOptionsModel{}))
+
+	allow := func(c Context, entity testOptionsModel) bool { return true }
+	policy := NewPolicy[testOptionsModel](enabled)
+	policy.
+		CanListAll(func(c Context) bool { return true }).
+		CanCreate(func(c Context) bool { return true }).
+		CanListById(allow).
+		CanWriteById(allow).
+		CanDeleteById(allow).
+		CanListHistory(allow).
+		CanRevertById(allow)
+
+	resource := FromModel[testOptionsModel]("entries", db, policy)
+	resource.CreateBindType(testBulkWrite{})
+	resource.WriteBindType(testBulkWrite{})
+
+	return db, resource
+}
+
+// testServer is a server on its own echo instance.
+type testServer struct {
+	*Server
+
+	e *echo.Echo
+}
+
+func newTestServer(t *testing.T, db *gorm.DB, resources []Provider, options ...Option) *testServer {
+	e := echo.New()
+	s, err := New(e, db, resources, options...)
+	assert.NoError(t, err)
+
+	return &testServer{Server: s, e: e}
+}
+
+// serve serves a JSON request. The headers are given as pairs of names and values.
+func (s *testServer) serve(method string, target string, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+	return rec
+}
@@ -0,0 +1,229 @@
+package sas
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/imthatgin/sas/pkg/migration"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HistoryEntry is a prior version of an entity, stored in the history table of its resource.
+// Version counts up from 1 for each entity, and Operation is the change which replaced the version. Each version
+// of an entity is unique, so concurrent changes of an entity fail with ErrorResourceConflict instead of both
+// storing the same version.
+type HistoryEntry struct {
+	ID uint `gorm:"primaryKey" json:"-"`
+
+	EntityID  uint      `gorm:"index" json:"entityId"`
+	Version   uint      `json:"version"`
+	Operation Operation `gorm:"size:32" json:"operation"`
+
+	Data json.RawMessage `json:"data"`
+
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// History enables the history table of the resource, which stores every prior version of an entity.
+// It adds GET /:id/history, GET /:id/history/:version and POST /:id/revert/:version, which are allowed by
+// the CanListHistory and CanRevertById predicates of the policy. Reverts are a write of the entity, so they are
+// also checked with CanWriteChange and validated. The table is created by the migration added
+// with RegisterHistoryMigration, which New adds to its migrator.
+func (mr *ModelResource[T]) History() {
+	mr.history = true
+}
+
+// HistoryTable returns the name of the history table of the resource, such as __sas_history_entries.
+func (mr *ModelResource[T]) HistoryTable() string {
+	name := strings.NewReplacer("/", "_", "-", "_").Replace(strings.Trim(mr.Name, "/"))
+	return "__sas_history_" + name
+}
+
+// RegisterHistoryMigration adds the system migration which creates the history table and its unique index of
+// versions to m, unless history is disabled or m already has it.
+func (mr *ModelResource[T]) RegisterHistoryMigration(m *migration.Migrator) {
+	if !mr.history {
+		return
+	}
+
+	table := mr.HistoryTable()
+	name := "000" + table
+	for _, existing := range m.Migrations() {
+		if existing.Name == name {
+			return
+		}
+	}
+
+	m.RegisterSystemMigration(name, func(db *gorm.DB) error {
+		if err := db.Table(table).AutoMigrate(&HistoryEntry{}); err != nil {
+			return err
+		}
+
+		return db.Exec("CREATE UNIQUE INDEX ? ON ? (?, ?)", clause.Column{Name: "idx" + table + "_version"},
+			clause.Table{Name: table}, clause.Column{Name: "entity_id"}, clause.Column{Name: "version"}).Error
+	}, func(db *gorm.DB) error {
+		return db.Migrator().DropTable(table)
+	})
+}
+
+func (mr *ModelResource[T]) historyRoutes() []resourceRoute {
+	if !mr.history {
+		return nil
+	}
+
+	return []resourceRoute{
+		{operation: OperationHistory, method: http.MethodGet, path: "/:id/history", handler: mr.getHistory},
+		{operation: OperationHistoryVersion, method: http.MethodGet, path: "/:id/history/:version", handler: mr.getHistoryVersion},
+		{operation: OperationRevert, method: http.MethodPost, path: "/:id/revert/:version", handler: mr.revert},
+	}
+}
+
+func (mr *ModelResource[T]) getHistory(c Context) error {
+	entity, err := mr.historyEntity(c)
+	if err != nil {
+		return err
+	}
+	if err := mr.authorize(c, OperationHistory, entity, mr.Policy.listHistory(c, *entity)); err != nil {
+		return err
+	}
+
+	q, err := Paginate(c, mr.database(c).Table(mr.HistoryTable()).Where("entity_id = ?", entityID(entity)).Order("version"))
+	if err != nil {
+		return err
+	}
+
+	entries := []HistoryEntry{}
+	if err := q.Find(&entries).Error; err != nil {
+		return errors.Join(ErrorDatabaseIssue, err)
+	}
+
+	return c.JSON(http.StatusOK, entries)
+}
+
+func (mr *ModelResource[T]) getHistoryVersion(c Context) error {
+	entity, err := mr.historyEntity(c)
+	if err != nil {
+		return err
+	}
+	if err := mr.authorize(c, OperationHistoryVersion, entity, mr.Policy.listHistory(c, *entity)); err != nil {
+		return err
+	}
+
+	version, err := mr.historyVersion(c, mr.database(c), entity)
+	if err != nil {
+		return err
+	}
+
+	if mr.readTransform != nil {
+		return c.JSON(http.StatusOK, mr.readTransform(*version))
+	}
+
+	return c.JSON(http.StatusOK, version)
+}
+
+func (mr *ModelResource[T]) revert(c Context) error {
+	entity, err := mr.historyEntity(c)
+	if err != nil {
+		return err
+	}
+	if err := mr.authorize(c, OperationRevert, entity, mr.Policy.revertById(c, *entity)); err != nil {
+		return err
+	}
+
+	err = mr.tracked(c, mr.database(c), OperationRevert, entity, func(tx *gorm.DB) (*T, error) {
+		version, err := mr.historyVersion(c, tx, entity)
+		if err != nil {
+			return nil, err
+		}
+
+		if mr.Policy.writeChange != nil {
+			if err := mr.authorize(c, OperationRevert, entity, mr.Policy.writeChange(c, *entity, *version)); err != nil {
+				return nil, err
+			}
+		}
+		if err := validate(c, version); err != nil {
+			return nil, err
+		}
+
+		if err := tx.Save(version).Error; err != nil {
+			return nil, errors.Join(ErrorDatabaseIssue, err)
+		}
+
+		return version, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// historyEntity loads the current entity of a history route.
+func (mr *ModelResource[T]) historyEntity(c Context) (*T, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, errors.Join(ErrorResourceInvalidID, err)
+	}
+
+	entity, err := mr.Queries.listByIdQuery(c, mr.database(c), uint(id))
+	if err != nil {
+		return nil, errors.Join(ErrorDatabaseIssue, err)
+	}
+
+	return entity, nil
+}
+
+// historyVersion loads the version named by the version parameter of entity. The version is decoded onto a copy
+// of entity, so the fields which are never encoded, such as secrets, keep their current value.
+func (mr *ModelResource[T]) historyVersion(c Context, db *gorm.DB, entity *T) (*T, error) {
+	id := entityID(entity)
+	number, err := strconv.ParseUint(c.Param("version"), 10, 0)
+	if err != nil {
+		return nil, errors.Join(ErrorResourceInvalidID, err)
+	}
+
+	var entry HistoryEntry
+	err = db.Table(mr.HistoryTable()).Where("entity_id = ? AND version = ?", id, number).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.Join(ErrorResourceNotFound, err)
+	}
+	if err != nil {
+		return nil, errors.Join(ErrorDatabaseIssue, err)
+	}
+
+	version := *entity
+	if err := json.Unmarshal(entry.Data, &version); err != nil {
+		return nil, errors.Join(ErrorDatabaseIssue, err)
+	}
+
+	return &version, nil
+}
+
+// recordHistory stores before as the next version of the entity, inside the transaction of the change.
+func (mr *ModelResource[T]) recordHistory(tx *gorm.DB, action Operation, id uint, before json.RawMessage) error {
+	var latest uint
+	err := tx.Table(mr.HistoryTable()).Where("entity_id = ?", id).Select("COALESCE(MAX(version), 0)").Scan(&latest).Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Table(mr.HistoryTable()).Create(&HistoryEntry{
+		EntityID:  id,
+		Version:   latest + 1,
+		Operation: action,
+		Data:      before,
+		Timestamp: time.Now().UTC(),
+	}).Error
+
+	// Another change of the entity stored the version first.
+	if translator, ok := tx.Dialector.(gorm.ErrorTranslator); ok && err != nil && errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey) {
+		return ErrorResourceConflict
+	}
+
+	return err
+}
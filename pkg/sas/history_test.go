@@ -0,0 +1,112 @@
+package sas
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/imthatgin/sas/pkg/migration"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestHistory(t *testing.T) {
+	db, resource := newTestEntries(t, endpoints.AllEndpoints)
+	resource.Policy.CanRevertById(func(c Context, entity testOptionsModel) bool {
+		return c.Request().Header.Get("X-User") == "admin"
+	})
+	resource.History()
+
+	other := FromModel[testOptionsModel]("other-entries", db, resource.Policy)
+	other.History()
+
+	s := newTestServer(t, db, []Provider{&resource, &other}, WithMigrations(migration.NewMigrator(), true))
+	assert.True(t, db.Migrator().HasTable("__sas_history_entries"))
+	assert.True(t, db.Migrator().HasTable("__sas_history_other_entries"))
+
+	serve := func(method string, target string, body string, user string) *httptest.ResponseRecorder {
+		return s.serve(method, target, body, "X-User", user)
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/entries", `{"Text":"first"}`, "").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/entries/1", `{"Text":"second"}`, "").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/entries/1", `{"Text":"third"}`, "").Code)
+
+	rec := serve(http.MethodGet, "/entries/1/history", "", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var entries []HistoryEntry
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &entries))
+	assert.Len(t, entries, 2)
+	assert.Equal(t, uint(1), entries[0].Version)
+	assert.Equal(t, OperationWrite, entries[0].Operation)
+	assert.Contains(t, string(entries[0].Data), `"Text":"first"`)
+	assert.Contains(t, string(entries[1].Data), `"Text":"second"`)
+
+	rec = serve(http.MethodGet, "/entries/1/history/1", "", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Text":"first"`)
+
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/entries/1/history/9", "", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/entries/1/history/first", "", "").Code)
+
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/entries/1/revert/1", "", "").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/entries/1/revert/1", "", "admin").Code)
+
+	rec = serve(http.MethodGet, "/entries/1", "", "")
+	assert.Contains(t, rec.Body.String(), `"Text":"first"`)
+
+	// The reverted state is kept as the next version.
+	rec = serve(http.MethodGet, "/entries/1/history", "", "")
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &entries))
+	assert.Len(t, entries, 3)
+	assert.Equal(t, OperationRevert, entries[2].Operation)
+	assert.Contains(t, string(entries[2].Data), `"Text":"third"`)
+
+	// Reverts are checked like the other writes.
+	resource.Policy.CanWriteChange(func(c Context, old testOptionsModel, new testOptionsModel) bool {
+		return new.Text != "third"
+	})
+	s.e.Validator = testHistoryValidator{}
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/entries/1/revert/3", "", "admin").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/entries/1/revert/2", "", "admin").Code)
+
+	// Each version of an entity is stored once, so the later of two concurrent changes conflicts.
+	err := db.Table(resource.HistoryTable()).Create(&HistoryEntry{EntityID: 1, Version: 1}).Error
+	assert.Error(t, err)
+
+	assert.NoError(t, db.Callback().Create().Before("gorm:create").Register("concurrent_change", func(tx *gorm.DB) {
+		if entry, ok := tx.Statement.Dest.(*HistoryEntry); ok {
+			tx.Session(&gorm.Session{NewDB: true}).Exec("INSERT INTO "+resource.HistoryTable()+" (entity_id, version) VALUES (?, ?)", entry.EntityID, entry.Version)
+		}
+	}))
+	rec = serve(http.MethodPut, "/entries/1", `{"Text":"conflict"}`, "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, ErrorResourceConflict.Error(), rec.Body.String())
+}
+
+// testHistoryValidator rejects the entities with the text second.
+type testHistoryValidator struct{}
+
+func (testHistoryValidator) Validate(i any) error {
+	if entity, ok := i.(*testOptionsModel); ok && entity.Text == "second" {
+		return errors.New("second is invalid")
+	}
+
+	return nil
+}
+
+func TestHistoryDisabled(t *testing.T) {
+	resource := FromModel[testOptionsModel]("entries", nil, NewPolicy[testOptionsModel](endpoints.AllEndpoints))
+
+	m := migration.NewMigrator()
+	resource.RegisterHistoryMigration(m)
+	assert.Empty(t, m.Migrations())
+
+	for _, route := range resource.Describe().Routes {
+		assert.NotEqual(t, OperationHistory, route.Operation)
+	}
+}
@@ -0,0 +1,97 @@
+package sas
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/imthatgin/sas/pkg/openapi"
+)
+
+// Resources describes every registered resource which implements Describer, including those of versions.
+// The paths of the routes include the base path of the server.
+func (s *Server) Resources() []ResourceDescription {
+	return describeResources(s.resources, s.config.basePath)
+}
+
+type resourcesReport struct {
+	Resources []resourceReport           `json:"resources"`
+	Schemas   map[string]*openapi.Schema `json:"schemas"`
+}
+
+type resourceReport struct {
+	Name       string                 `json:"name"`
+	Version    string                 `json:"version,omitempty"`
+	Deprecated bool                   `json:"deprecated,omitempty"`
+	Endpoints  []string               `json:"endpoints"`
+	Routes     []routeReport          `json:"routes"`
+	Predicates []PredicateDescription `json:"predicates"`
+
+	Model          *openapi.Schema `json:"model"`
+	ReadType       *openapi.Schema `json:"readType,omitempty"`
+	CreateBindType *openapi.Schema `json:"createBindType,omitempty"`
+	WriteBindType  *openapi.Schema `json:"writeBindType,omitempty"`
+}
+
+type routeReport struct {
+	Operation Operation       `json:"operation"`
+	Method    string          `json:"method"`
+	Path      string          `json:"path"`
+	Action    string          `json:"action,omitempty"`
+	Request   *openapi.Schema `json:"request,omitempty"`
+}
+
+// ServeResources serves a JSON report of the registered resources at path under the base path, such as
+// /_sas/resources. It lists the routes, enabled endpoints, types and customized policy predicates of each resource.
+// can decides who may read the report; a nil can denies every call.
+func (s *Server) ServeResources(path string, can func(c Context) bool) {
+	s.serveGet(path, func(c Context) error {
+		if can == nil || !can(c) {
+			return ErrorResourceNoAccess
+		}
+
+		return c.JSON(http.StatusOK, s.resourcesReport())
+	})
+}
+
+func (s *Server) resourcesReport() resourcesReport {
+	schemas := openapi.NewSchemas()
+	schemaOf := func(t reflect.Type) *openapi.Schema {
+		if t == nil {
+			return nil
+		}
+		return schemas.For(t)
+	}
+
+	report := resourcesReport{Resources: []resourceReport{}}
+	for _, description := range s.Resources() {
+		resource := resourceReport{
+			Name:       description.Name,
+			Version:    description.Version,
+			Deprecated: description.Deprecated,
+			Endpoints:  endpoints.Names(description.Endpoints),
+			Routes:     []routeReport{},
+			Predicates: description.Predicates,
+
+			Model:          schemaOf(description.Model),
+			ReadType:       schemaOf(description.ReadType),
+			CreateBindType: schemaOf(description.CreateBindType),
+			WriteBindType:  schemaOf(description.WriteBindType),
+		}
+
+		for _, route := range description.Routes {
+			resource.Routes = append(resource.Routes, routeReport{
+				Operation: route.Operation,
+				Method:    route.Method,
+				Path:      route.Path,
+				Action:    route.Action,
+				Request:   schemaOf(route.RequestType),
+			})
+		}
+
+		report.Resources = append(report.Resources, resource)
+	}
+	report.Schemas = schemas.Components()
+
+	return report
+}
@@ -0,0 +1,85 @@
+package sas
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_Resources(t *testing.T) {
+	policy := NewPolicy[testOpenAPIModel](endpoints.GET | endpoints.DELETE)
+	policy.CanListAll(func(c Context) bool {
+		return true
+	})
+
+	resource := FromModel[testOpenAPIModel]("posts", nil, policy)
+	resource.WriteBindType(testOpenAPIWrite{})
+
+	e := echo.New()
+	s, err := New(e, nil, []Provider{&resource}, WithoutMigrations())
+	assert.NoError(t, err)
+
+	resources := s.Resources()
+	assert.Len(t, resources, 1)
+	assert.Equal(t, "posts", resources[0].Name)
+	assert.Equal(t, []RouteDescription{
+		{Operation: OperationList, Method: http.MethodGet, Path: "/posts"},
+		{Operation: OperationGet, Method: http.MethodGet, Path: "/posts/:id"},
+		{Operation: OperationDelete, Method: http.MethodDelete, Path: "/posts/:id"},
+	}, resources[0].Routes)
+	assert.Contains(t, resources[0].Predicates, PredicateDescription{Name: "CanListAll", Customized: true, Default: EffectDeny})
+	assert.Contains(t, resources[0].Predicates, PredicateDescription{Name: "CanDeleteById", Customized: false, Default: EffectDeny})
+	assert.Contains(t, resources[0].Predicates, PredicateDescription{Name: "CanWriteChange", Customized: false, Default: EffectAllow})
+
+	s.ServeResources("/_sas/resources", func(c Context) bool {
+		return true
+	})
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_sas/resources", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var report resourcesReport
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, []string{"GET", "DELETE"}, report.Resources[0].Endpoints)
+	assert.Nil(t, report.Resources[0].CreateBindType)
+	assert.NotNil(t, report.Resources[0].WriteBindType)
+	assert.Contains(t, report.Schemas, "testOpenAPIModel")
+}
+
+func TestServer_ServeResources_Router(t *testing.T) {
+	resource := FromModel[testOpenAPIModel]("posts", nil, NewPolicy[testOpenAPIModel](endpoints.GET))
+
+	e := echo.New()
+	s, err := New(e, nil, []Provider{&resource},
+		WithoutMigrations(),
+		WithBasePath("/api"),
+		WithAuthenticators(StaticTokens(map[string]*Principal{"admin": {ID: "admin", Roles: []string{"admin"}}})),
+	)
+	assert.NoError(t, err)
+
+	// The report is served under the base path, and can sees the principal of the request.
+	s.ServeResources("/_sas/resources", func(c Context) bool {
+		return HasRole("admin")(PrincipalFrom(c))
+	})
+	s.ServeResources("/_sas/denied", nil)
+
+	serve := func(target string, token string) int {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("/api/_sas/resources", "admin"))
+	assert.Equal(t, http.StatusForbidden, serve("/api/_sas/resources", ""))
+	assert.Equal(t, http.StatusNotFound, serve("/_sas/resources", "admin"))
+	assert.Equal(t, http.StatusForbidden, serve("/api/_sas/denied", "admin"))
+}
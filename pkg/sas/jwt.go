@@ -0,0 +1,154 @@
+package sas
+
+import (
+	"crypto/rsa"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTAuthenticator authenticates bearer tokens which are JWTs signed with a local key.
+// A []byte key verifies HS256, HS384 and HS512 tokens, and an *rsa.PublicKey verifies RS256, RS384 and RS512
+// tokens. The principal is read from the sub claim, and the roles, scope and tenant claims by default.
+// Tokens are verified with github.com/golang-jwt/jwt, which checks the exp and nbf claims when tokens have
+// them, see RequireExpiry.
+type JWTAuthenticator struct {
+	key any
+
+	issuer   string
+	audience string
+
+	rolesClaim  string
+	scopesClaim string
+	tenantClaim string
+
+	requireExpiry bool
+
+	leeway time.Duration
+	now    func() time.Time
+}
+
+// NewJWTAuthenticator creates an authenticator verifying tokens with key.
+func NewJWTAuthenticator(key any) *JWTAuthenticator {
+	return &JWTAuthenticator{
+		key: key,
+
+		rolesClaim:  "roles",
+		scopesClaim: "scope",
+		tenantClaim: "tenant",
+
+		leeway: time.Minute,
+		now:    time.Now,
+	}
+}
+
+// Issuer requires the iss claim of tokens to be issuer.
+func (a *JWTAuthenticator) Issuer(issuer string) *JWTAuthenticator {
+	a.issuer = issuer
+	return a
+}
+
+// Audience requires the aud claim of tokens to contain audience.
+func (a *JWTAuthenticator) Audience(audience string) *JWTAuthenticator {
+	a.audience = audience
+	return a
+}
+
+// RequireExpiry rejects tokens without an exp claim. By default the exp claim is optional, and tokens without
+// one never expire.
+func (a *JWTAuthenticator) RequireExpiry() *JWTAuthenticator {
+	a.requireExpiry = true
+	return a
+}
+
+// Claims sets the claims the roles, scopes and tenant of the principal are read from. The roles and scopes can
+// be arrays, or strings separated by spaces.
+func (a *JWTAuthenticator) Claims(roles string, scopes string, tenant string) *JWTAuthenticator {
+	a.rolesClaim = roles
+	a.scopesClaim = scopes
+	a.tenantClaim = tenant
+	return a
+}
+
+// Authenticate verifies the bearer token of the request. Bearer tokens which are not JWTs, or are signed with
+// an algorithm the key does not verify, are left to the next authenticator.
+func (a *JWTAuthenticator) Authenticate(c Context) (*Principal, error) {
+	raw := bearerToken(c)
+	if strings.Count(raw, ".") != 2 {
+		return nil, nil
+	}
+
+	methods := a.methods()
+	unverified, _, err := jwt.NewParser().ParseUnverified(raw, jwt.MapClaims{})
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(methods, unverified.Method.Alg()) {
+		return nil, nil
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithLeeway(a.leeway),
+		jwt.WithTimeFunc(a.now),
+	}
+	if a.issuer != "" {
+		options = append(options, jwt.WithIssuer(a.issuer))
+	}
+	if a.audience != "" {
+		options = append(options, jwt.WithAudience(a.audience))
+	}
+	if a.requireExpiry {
+		options = append(options, jwt.WithExpirationRequired())
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.NewParser(options...).ParseWithClaims(raw, claims, func(*jwt.Token) (any, error) {
+		return a.key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	principal := &Principal{
+		Roles:  claimStrings(claims[a.rolesClaim]),
+		Scopes: claimStrings(claims[a.scopesClaim]),
+		Claims: claims,
+	}
+	principal.ID, _ = claims["sub"].(string)
+	principal.Tenant, _ = claims[a.tenantClaim].(string)
+
+	return principal, nil
+}
+
+// methods returns the signing algorithms the key verifies.
+func (a *JWTAuthenticator) methods() []string {
+	switch a.key.(type) {
+	case []byte:
+		return []string{"HS256", "HS384", "HS512"}
+	case *rsa.PublicKey:
+		return []string{"RS256", "RS384", "RS512"}
+	}
+
+	return nil
+}
+
+// claimStrings reads a claim which is an array of strings, or a single string separated by spaces.
+func claimStrings(claim any) []string {
+	switch claim := claim.(type) {
+	case string:
+		return strings.Fields(claim)
+	case []any:
+		var values []string
+		for _, value := range claim {
+			if s, ok := value.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+
+	return nil
+}
@@ -4,12 +4,13 @@ import (
 	"errors"
 	patch "github.com/geraldo-labs/merge-struct"
 	"github.com/imthatgin/sas/pkg/endpoints"
-	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
 	"gorm.io/gorm"
 	"net/http"
 	"reflect"
+	"slices"
 	"strconv"
+	"strings"
 )
 
 type ModelResource[T any] struct {
@@ -24,10 +25,26 @@ type ModelResource[T any] struct {
 	createBindType any
 	writeBindType  any
 
-	createTransformer func(c echo.Context) (*T, error)
+	// Reading
+	readType      any
+	readTransform func(entity T) any
 
-	middlewares []echo.MiddlewareFunc
-	onRegister  func(e *echo.Echo)
+	createTransformer func(c Context) (*T, error)
+
+	bulkOptions BulkOptions
+	auditor     *Auditor
+	history     bool
+	events      *Broker
+	webhooks    *Webhooks
+	outbox      *Outbox
+	decisions   *DecisionLog
+	tenancy     *Tenancy
+
+	actions     []resourceRoute
+	middlewares []MiddlewareFunc
+	onRegister  func(r Router)
+
+	docs ResourceDocs
 }
 
 func FromModel[T any](name string, db *gorm.DB, policy Policy[T]) ModelResource[T] {
@@ -44,68 +61,88 @@ func FromModel[T any](name string, db *gorm.DB, policy Policy[T]) ModelResource[
 	return mr
 }
 
-// Register is called automatically by SAS, and will add the configured endpoint behaviours to echo.
-func (mr *ModelResource[T]) Register(e *echo.Echo) {
-	group := e.Group(mr.Name)
-
-	if endpoints.Has(mr.Policy.EnabledEndpoints, endpoints.GET) {
-		group.GET("", mr.getAll, mr.middlewares...)
-	}
-
-	if endpoints.Has(mr.Policy.EnabledEndpoints, endpoints.GET) {
-		group.GET("/:id", mr.getById, mr.middlewares...)
-	}
+// Register is called automatically by SAS, and will add the configured endpoint behaviours to the router.
+func (mr *ModelResource[T]) Register(r Router) {
+	group := r.Group("/" + strings.Trim(mr.Name, "/"))
 
-	if endpoints.Has(mr.Policy.EnabledEndpoints, endpoints.PUT) {
-		group.PUT("/:id", mr.writeById, mr.middlewares...)
+	// The tenant is resolved after the middlewares of the resource, so it can be read from the principal of
+	// authentication added there.
+	middlewares := slices.Clone(mr.middlewares)
+	if mr.tenancy != nil {
+		middlewares = append(middlewares, mr.tenancy.Middleware())
 	}
 
-	if endpoints.Has(mr.Policy.EnabledEndpoints, endpoints.POST) {
-		group.POST("", mr.create, mr.middlewares...)
-	}
-
-	if endpoints.Has(mr.Policy.EnabledEndpoints, endpoints.DELETE) {
-		group.DELETE("/:id", mr.deleteById, mr.middlewares...)
+	for _, route := range mr.routes() {
+		if endpoints.Has(mr.Policy.EnabledEndpoints, route.endpoint) {
+			group.Add(route.method, route.path, route.handler, middlewares...)
+		}
 	}
 
 	if mr.onRegister != nil {
-		mr.onRegister(e)
+		mr.onRegister(r)
 	}
 }
 
-func (mr *ModelResource[T]) getAll(c echo.Context) error {
-	if !mr.Policy.canListAll(c) {
-		return ErrorResourceNoAccess
+// routes lists every route the resource can serve, relative to its name.
+func (mr *ModelResource[T]) routes() []resourceRoute {
+	return append([]resourceRoute{
+		{operation: OperationList, endpoint: endpoints.GET, method: http.MethodGet, path: "", handler: mr.getAll},
+		{operation: OperationGet, endpoint: endpoints.GET, method: http.MethodGet, path: "/:id", handler: mr.getById},
+		{operation: OperationWrite, endpoint: endpoints.PUT, method: http.MethodPut, path: "/:id", handler: mr.writeById},
+		{operation: OperationCreate, endpoint: endpoints.POST, method: http.MethodPost, path: "", handler: mr.create},
+		{operation: OperationDelete, endpoint: endpoints.DELETE, method: http.MethodDelete, path: "/:id", handler: mr.deleteById},
+		{operation: OperationBulkCreate, endpoint: endpoints.BULK | endpoints.POST, method: http.MethodPost, path: "/bulk", handler: mr.bulkCreate},
+		{operation: OperationBulkWrite, endpoint: endpoints.BULK | endpoints.PATCH, method: http.MethodPatch, path: "/bulk", handler: mr.bulkWrite},
+		{operation: OperationBulkDelete, endpoint: endpoints.BULK | endpoints.DELETE, method: http.MethodDelete, path: "", handler: mr.bulkDelete},
+	}, slices.Concat(mr.historyRoutes(), mr.eventRoutes(), mr.actions)...)
+}
+
+func (mr *ModelResource[T]) getAll(c Context) error {
+	if err := mr.authorize(c, OperationList, nil, mr.Policy.listAll(c)); err != nil {
+		return err
 	}
 
-	result, err := mr.Queries.listAllQuery(c, mr.db)
+	result, err := mr.Queries.listAllQuery(c, mr.database(c))
 	if err != nil {
 		return errors.Join(ErrorDatabaseIssue, err)
 	}
 
+	if mr.readTransform != nil {
+		read := make([]any, 0, len(result))
+		for _, entity := range result {
+			read = append(read, mr.readTransform(entity))
+		}
+
+		return c.JSON(http.StatusOK, read)
+	}
+
 	return c.JSON(http.StatusOK, result)
 }
 
-func (mr *ModelResource[T]) getById(c echo.Context) error {
+func (mr *ModelResource[T]) getById(c Context) error {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		return errors.Join(ErrorResourceInvalidID, err)
 	}
 
-	result, err := mr.Queries.listByIdQuery(c, mr.db, uint(id))
+	result, err := mr.Queries.listByIdQuery(c, mr.database(c), uint(id))
 	if err != nil {
 		return errors.Join(ErrorDatabaseIssue, err)
 	}
 
-	if !mr.Policy.canListById(c, *result) {
-		return ErrorResourceNoAccess
+	if err := mr.authorize(c, OperationGet, result, mr.Policy.listById(c, *result)); err != nil {
+		return err
+	}
+
+	if mr.readTransform != nil {
+		return c.JSON(http.StatusOK, mr.readTransform(*result))
 	}
 
 	return c.JSON(http.StatusOK, result)
 }
 
-func (mr *ModelResource[T]) writeById(c echo.Context) error {
+func (mr *ModelResource[T]) writeById(c Context) error {
 	// Check that we have a bind type set up already. If not, we must fail the call.
 	if mr.writeBindType == nil {
 		return ErrorFatalSetupNoBindType
@@ -118,6 +155,9 @@ func (mr *ModelResource[T]) writeById(c echo.Context) error {
 	if err := c.Bind(bound); err != nil {
 		return errors.Join(ErrorResourceInvalidData, err)
 	}
+	if err := validate(c, bound); err != nil {
+		return err
+	}
 
 	// Parse the ID parameter, or fail.
 	idStr := c.Param("id")
@@ -126,26 +166,35 @@ func (mr *ModelResource[T]) writeById(c echo.Context) error {
 		return errors.Join(ErrorResourceInvalidID, err)
 	}
 
-	result, err := mr.Queries.listByIdQuery(c, mr.db, uint(id))
+	result, err := mr.Queries.listByIdQuery(c, mr.database(c), uint(id))
 	if err != nil {
 		return errors.Join(ErrorDatabaseIssue, err)
 	}
 
-	if !mr.Policy.canListById(c, *result) {
-		return ErrorResourceNoAccess
+	if err := mr.authorize(c, OperationWrite, result, mr.Policy.writeById(c, *result)); err != nil {
+		return err
+	}
+	if err := mr.authorizeChange(c, OperationWrite, result, bound); err != nil {
+		return err
 	}
 
-	err = mr.Queries.writeByIdQuery(c, mr.db, result, bound)
+	err = mr.tracked(c, mr.database(c), OperationWrite, result, func(tx *gorm.DB) (*T, error) {
+		if err := mr.Queries.writeByIdQuery(c, tx, result, bound); err != nil {
+			return nil, errors.Join(ErrorDatabaseIssue, err)
+		}
+
+		return result, nil
+	})
 	if err != nil {
-		return errors.Join(ErrorDatabaseIssue, err)
+		return err
 	}
 
 	return c.NoContent(http.StatusOK)
 }
 
-func (mr *ModelResource[T]) create(c echo.Context) error {
-	if !mr.Policy.canCreate(c) {
-		return ErrorResourceNoAccess
+func (mr *ModelResource[T]) create(c Context) error {
+	if err := mr.authorize(c, OperationCreate, nil, mr.Policy.create(c)); err != nil {
+		return err
 	}
 
 	// Patch data onto the structure.
@@ -172,6 +221,9 @@ func (mr *ModelResource[T]) create(c echo.Context) error {
 			log.Error("Binding failed: ", err)
 			return err
 		}
+		if err := validate(c, bound); err != nil {
+			return err
+		}
 
 		_, err := patch.Struct(&model, bound)
 		if err != nil {
@@ -180,39 +232,62 @@ func (mr *ModelResource[T]) create(c echo.Context) error {
 		}
 	}
 
-	tx := mr.db.Create(&model)
-	if tx.Error != nil {
-		return errors.Join(ErrorDatabaseIssue, tx.Error)
+	if err := assignTenant(c, &model); err != nil {
+		return err
+	}
+	if err := mr.authorizeEntity(c, OperationCreate, &model); err != nil {
+		return err
+	}
+
+	err := mr.tracked(c, mr.database(c), OperationCreate, nil, func(tx *gorm.DB) (*T, error) {
+		if err := tx.Create(&model).Error; err != nil {
+			return nil, errors.Join(ErrorDatabaseIssue, err)
+		}
+
+		return &model, nil
+	})
+	if err != nil {
+		return err
 	}
 
 	return c.NoContent(http.StatusOK)
 }
 
-func (mr *ModelResource[T]) deleteById(c echo.Context) error {
+func (mr *ModelResource[T]) deleteById(c Context) error {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		return errors.Join(ErrorResourceInvalidID, err)
 	}
 
-	var result T
-	tx := mr.db.First(&result, "id = ?", id)
-	if tx.Error != nil {
+	result, err := mr.Queries.listByIdQuery(c, mr.database(c), uint(id))
+	if err != nil {
 		return errors.Join(ErrorDatabaseIssue, err)
 	}
 
-	if !mr.Policy.canDeleteById(c, result) {
-		return ErrorResourceNoAccess
+	if err := mr.authorize(c, OperationDelete, result, mr.Policy.deleteById(c, *result)); err != nil {
+		return err
 	}
 
-	err = mr.Queries.deleteByIdQuery(c, mr.db, result)
+	err = mr.tracked(c, mr.database(c), OperationDelete, result, func(tx *gorm.DB) (*T, error) {
+		if err := mr.Queries.deleteByIdQuery(c, tx, *result); err != nil {
+			return nil, errors.Join(ErrorDatabaseIssue, err)
+		}
+
+		return nil, nil
+	})
 	if err != nil {
-		return errors.Join(ErrorDatabaseIssue, err)
+		return err
 	}
 
 	return c.NoContent(http.StatusOK)
 }
 
+// Model returns a pointer to a zero value of the model type, as used by GORM.
+func (mr *ModelResource[T]) Model() any {
+	return new(T)
+}
+
 func (mr *ModelResource[T]) CreateBindType(bt any) {
 	mr.createBindType = bt
 }
@@ -221,10 +296,20 @@ func (mr *ModelResource[T]) WriteBindType(bt any) {
 	mr.writeBindType = bt
 }
 
-func (mr *ModelResource[T]) OnRegister(handler func(e *echo.Echo)) {
+// ReadType sets the type returned by the list and get endpoints instead of the model, such as a
+// version specific representation. transform converts each entity into a value of that type.
+func (mr *ModelResource[T]) ReadType(rt any, transform func(entity T) any) {
+	mr.readType = rt
+	mr.readTransform = transform
+}
+
+// OnRegister is called after the routes of the resource are registered, with the router of the server,
+// which is where additional routes can be added under the base path of the server.
+func (mr *ModelResource[T]) OnRegister(handler func(r Router)) {
 	mr.onRegister = handler
 }
 
-func (mr *ModelResource[T]) Middlewares(middlewares ...echo.MiddlewareFunc) {
+// Middlewares are run for every route of the resource, before its tenant is resolved.
+func (mr *ModelResource[T]) Middlewares(middlewares ...MiddlewareFunc) {
 	mr.middlewares = middlewares
 }
@@ -0,0 +1,371 @@
+package sas
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/imthatgin/sas/pkg/openapi"
+)
+
+var pathParamPattern = regexp.MustCompile(`:(\w+)`)
+
+// Names of the shared error responses in the components of the OpenAPI document.
+const (
+	responseBadRequest = "BadRequest"
+	responseForbidden  = "Forbidden"
+	responseNotFound   = "NotFound"
+	responseInternal   = "InternalError"
+)
+
+// OpenAPI builds an OpenAPI document describing every resource which implements Describer.
+func (s *Server) OpenAPI(info openapi.Info) *openapi.Document {
+	schemas := openapi.NewSchemas()
+
+	doc := &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info:    info,
+		Paths:   map[string]*openapi.PathItem{},
+		Components: &openapi.Components{
+			Responses: errorResponses(),
+		},
+	}
+
+	for _, description := range s.Resources() {
+		tags := description.Docs.Tags
+		if len(tags) == 0 {
+			tags = []string{strings.Trim(description.Name, "/")}
+		}
+		for _, tag := range tags {
+			doc.Tags = appendTag(doc.Tags, openapi.Tag{Name: tag, Description: description.Docs.Description})
+		}
+
+		for _, route := range description.Routes {
+			path, parameters := openAPIPath(route.Path)
+
+			item, ok := doc.Paths[path]
+			if !ok {
+				item = &openapi.PathItem{}
+				doc.Paths[path] = item
+			}
+
+			operation := describeOperation(schemas, description, route)
+			operation.Tags = tags
+			operation.Parameters = append(parameters, operation.Parameters...)
+
+			item.SetOperation(route.Method, operation)
+		}
+	}
+
+	doc.Components.Schemas = schemas.Components()
+
+	return doc
+}
+
+// ServeOpenAPI serves the OpenAPI document of the server as JSON at path, under the base path.
+func (s *Server) ServeOpenAPI(path string, info openapi.Info) {
+	s.serveGet(path, func(c Context) error {
+		return c.JSON(http.StatusOK, s.OpenAPI(info))
+	})
+}
+
+func describeOperation(schemas *openapi.Schemas, description ResourceDescription, route RouteDescription) *openapi.Operation {
+	name := strings.Trim(description.Name, "/")
+	id := exportedName(name) + exportedName(description.Version)
+
+	model := schemas.For(description.Model)
+	if description.ReadType != nil {
+		model = schemas.For(description.ReadType)
+	}
+
+	operation := &openapi.Operation{
+		OperationID: string(route.Operation) + id,
+		Description: description.Docs.Description,
+		Deprecated:  description.Deprecated,
+		Responses: map[string]*openapi.Response{
+			"403": {Ref: "#/components/responses/" + responseForbidden},
+			"500": {Ref: "#/components/responses/" + responseInternal},
+		},
+	}
+
+	switch route.Operation {
+	case OperationList:
+		operation.Summary = "List " + name
+		operation.Parameters = paginationParameters()
+		operation.Responses["200"] = &openapi.Response{
+			Description: "The entities the caller may list",
+			Content:     openapi.JSON(&openapi.Schema{Type: "array", Items: model}),
+		}
+
+	case OperationGet:
+		operation.Summary = "Get " + name + " by ID"
+		operation.Responses["200"] = &openapi.Response{
+			Description: "The requested entity",
+			Content:     openapi.JSON(model),
+		}
+
+	case OperationCreate:
+		operation.Summary = "Create " + name
+		operation.RequestBody = requestBody(schemas, description.CreateBindType)
+		operation.Responses["200"] = &openapi.Response{Description: "The entity was created"}
+
+	case OperationWrite:
+		operation.Summary = "Update " + name + " by ID"
+		if route.Method == http.MethodPatch {
+			operation.OperationID = "patch" + id
+			operation.Summary = "Partially update " + name + " by ID"
+		}
+		operation.RequestBody = requestBody(schemas, description.WriteBindType)
+		operation.Responses["200"] = &openapi.Response{Description: "The entity was updated"}
+
+	case OperationDelete:
+		operation.Summary = "Delete " + name + " by ID"
+		operation.Responses["200"] = &openapi.Response{Description: "The entity was deleted"}
+
+	case OperationBulkCreate:
+		operation.Summary = "Create many " + name
+		operation.Parameters = []*openapi.Parameter{bulkModeParameter()}
+		operation.RequestBody = bulkRequestBody(schemas, description.CreateBindType)
+		operation.Responses = bulkResponses(schemas, operation.Responses)
+
+	case OperationBulkWrite:
+		operation.Summary = "Partially update many " + name
+		operation.Parameters = []*openapi.Parameter{bulkModeParameter()}
+
+		changes := &openapi.Schema{}
+		if description.WriteBindType != nil {
+			changes = schemas.For(description.WriteBindType)
+		}
+		item := &openapi.Schema{
+			Type: "object",
+			Properties: map[string]*openapi.Schema{
+				"id":      {Type: "integer", Format: "int64", Minimum: new(float64)},
+				"changes": changes,
+			},
+			Required: []string{"id", "changes"},
+		}
+		operation.RequestBody = &openapi.RequestBody{
+			Required: true,
+			Content:  openapi.JSON(&openapi.Schema{Type: "array", Items: item}),
+		}
+		operation.Responses = bulkResponses(schemas, operation.Responses)
+
+	case OperationBulkDelete:
+		operation.Summary = "Delete many " + name + " by ID"
+		operation.Parameters = []*openapi.Parameter{
+			{
+				Name:        "ids",
+				In:          "query",
+				Description: "Comma separated IDs of the entities to delete",
+				Required:    true,
+				Schema:      &openapi.Schema{Type: "string"},
+			},
+			bulkModeParameter(),
+		}
+		operation.Responses = bulkResponses(schemas, operation.Responses)
+		operation.Responses["400"] = &openapi.Response{Ref: "#/components/responses/" + responseBadRequest}
+
+	case OperationHistory:
+		operation.Summary = "List prior versions of " + name + " by ID"
+		operation.Parameters = paginationParameters()
+		operation.Responses["200"] = &openapi.Response{
+			Description: "The prior versions of the entity, oldest first",
+			Content:     openapi.JSON(&openapi.Schema{Type: "array", Items: schemas.For(reflect.TypeOf(HistoryEntry{}))}),
+		}
+
+	case OperationHistoryVersion:
+		operation.Summary = "Get a prior version of " + name + " by ID"
+		operation.Responses["200"] = &openapi.Response{
+			Description: "The entity as it was at the version",
+			Content:     openapi.JSON(model),
+		}
+
+	case OperationRevert:
+		operation.Summary = "Revert " + name + " by ID to a prior version"
+		operation.Responses["200"] = &openapi.Response{Description: "The entity was reverted"}
+
+	case OperationEvents:
+		operation.Summary = "Stream changes to " + name
+		operation.Parameters = eventsParameters()
+		operation.Responses["200"] = &openapi.Response{
+			Description: "Server-Sent Events named after the type of the change, with the entity as data",
+			Content: map[string]*openapi.MediaType{
+				"text/event-stream": {Schema: schemas.For(reflect.TypeOf(Event{}))},
+			},
+		}
+
+	case OperationEventsWebSocket:
+		operation.Summary = "Stream changes to " + name + " over a WebSocket"
+		operation.Parameters = eventsParameters()
+		operation.Responses["101"] = &openapi.Response{Description: "Switches to a WebSocket sending each event as JSON"}
+
+	case OperationAction:
+		operation.OperationID = lowerFirst(exportedName(route.Action)) + id
+		operation.Summary = strings.ReplaceAll(route.Action, "-", " ") + " " + name
+		if strings.Contains(route.Path, ":") {
+			operation.Summary += " by ID"
+		}
+		if route.RequestType != nil && route.RequestType.Kind() == reflect.Struct && route.RequestType.NumField() > 0 {
+			operation.RequestBody = requestBody(schemas, route.RequestType)
+		}
+		operation.Responses["200"] = &openapi.Response{Description: "The action succeeded"}
+
+	default:
+		operation.Summary = string(route.Operation) + " " + name
+		operation.Responses["200"] = &openapi.Response{Description: "The operation succeeded"}
+	}
+
+	if operation.RequestBody != nil {
+		operation.Responses["400"] = &openapi.Response{Ref: "#/components/responses/" + responseBadRequest}
+	}
+	if strings.Contains(route.Path, ":") {
+		operation.Responses["400"] = &openapi.Response{Ref: "#/components/responses/" + responseBadRequest}
+		operation.Responses["404"] = &openapi.Response{Ref: "#/components/responses/" + responseNotFound}
+	}
+
+	if summary, ok := description.Docs.Operations[route.Operation]; ok {
+		operation.Summary = summary
+	} else if description.Docs.Summary != "" {
+		operation.Summary = description.Docs.Summary + ": " + operation.Summary
+	}
+
+	return operation
+}
+
+func bulkRequestBody(schemas *openapi.Schemas, bindType reflect.Type) *openapi.RequestBody {
+	items := &openapi.Schema{}
+	if bindType != nil {
+		items = schemas.For(bindType)
+	}
+
+	return &openapi.RequestBody{
+		Required: true,
+		Content:  openapi.JSON(&openapi.Schema{Type: "array", Items: items}),
+	}
+}
+
+func bulkModeParameter() *openapi.Parameter {
+	return &openapi.Parameter{
+		Name:        "mode",
+		In:          "query",
+		Description: "atomic to apply every item or none of them, which is the default, or partial to apply the items which succeed",
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+}
+
+// bulkResponses adds the per item results, which are returned for both successful and failed bulk requests.
+func bulkResponses(schemas *openapi.Schemas, responses map[string]*openapi.Response) map[string]*openapi.Response {
+	results := openapi.JSON(schemas.For(reflect.TypeOf(BulkResponse{})))
+
+	responses["200"] = &openapi.Response{
+		Description: "Every item succeeded. When an item of an atomic request fails, nothing is applied and " +
+			"the response has the status of that item, with the same body",
+		Content: results,
+	}
+	responses["207"] = &openapi.Response{Description: "Some items of a partial request failed", Content: results}
+
+	return responses
+}
+
+func eventsParameters() []*openapi.Parameter {
+	return []*openapi.Parameter{
+		{
+			Name:        "lastEventId",
+			In:          "query",
+			Description: "Resumes after the event with this ID, like the Last-Event-ID header",
+			Schema:      &openapi.Schema{Type: "integer", Format: "int64", Minimum: new(float64)},
+		},
+	}
+}
+
+func requestBody(schemas *openapi.Schemas, bindType reflect.Type) *openapi.RequestBody {
+	schema := &openapi.Schema{}
+	if bindType != nil {
+		schema = schemas.For(bindType)
+	}
+
+	return &openapi.RequestBody{
+		Required: true,
+		Content:  openapi.JSON(schema),
+	}
+}
+
+// openAPIPath converts an echo path such as /entries/:id into /entries/{id}, and returns its parameters.
+func openAPIPath(path string) (string, []*openapi.Parameter) {
+	var parameters []*openapi.Parameter
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		schema := &openapi.Schema{Type: "string"}
+		if match[1] == "id" || match[1] == "version" || strings.HasSuffix(match[1], "Id") {
+			schema = &openapi.Schema{Type: "integer", Format: "int64", Minimum: new(float64)}
+		}
+
+		parameters = append(parameters, &openapi.Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   schema,
+		})
+	}
+
+	return pathParamPattern.ReplaceAllString(path, "{$1}"), parameters
+}
+
+func paginationParameters() []*openapi.Parameter {
+	integer := &openapi.Schema{Type: "integer", Minimum: new(float64)}
+
+	return []*openapi.Parameter{
+		{Name: "limit", In: "query", Description: "Maximum number of entities to return", Schema: integer},
+		{Name: "offset", In: "query", Description: "Number of entities to skip", Schema: integer},
+	}
+}
+
+func errorResponses() map[string]*openapi.Response {
+	text := func(description string) *openapi.Response {
+		return &openapi.Response{
+			Description: description,
+			Content: map[string]*openapi.MediaType{
+				"text/plain": {Schema: &openapi.Schema{Type: "string"}},
+			},
+		}
+	}
+
+	return map[string]*openapi.Response{
+		responseBadRequest: text(ErrorResourceInvalidID.Error() + ", or " + ErrorResourceInvalidData.Error()),
+		responseForbidden:  text(ErrorResourceNoAccess.Error()),
+		responseNotFound:   text(ErrorResourceNotFound.Error()),
+		responseInternal:   text(ErrorDatabaseIssue.Error()),
+	}
+}
+
+func appendTag(tags []openapi.Tag, tag openapi.Tag) []openapi.Tag {
+	for _, existing := range tags {
+		if existing.Name == tag.Name {
+			return tags
+		}
+	}
+
+	return append(tags, tag)
+}
+
+func lowerFirst(name string) string {
+	if name == "" {
+		return name
+	}
+
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// exportedName turns a resource name such as "blog-posts" into "BlogPosts".
+func exportedName(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+
+	var result strings.Builder
+	for _, part := range parts {
+		result.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+
+	return result.String()
+}
@@ -0,0 +1,89 @@
+package sas
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/imthatgin/sas/pkg/openapi"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type testOpenAPIModel struct {
+	DefaultModel
+
+	Title    string `json:"title"`
+	Body     *string
+	internal string
+}
+
+type testOpenAPIWrite struct {
+	Title string `json:"title" validate:"required"`
+	Draft bool   `json:"draft,omitempty"`
+}
+
+func TestServer_OpenAPI(t *testing.T) {
+	resource := FromModel[testOpenAPIModel]("posts", nil, NewPolicy[testOpenAPIModel](endpoints.GET|endpoints.PUT))
+	resource.WriteBindType(testOpenAPIWrite{})
+	resource.Docs(ResourceDocs{
+		Tags:       []string{"blog"},
+		Operations: map[Operation]string{OperationList: "Every post"},
+	})
+
+	e := echo.New()
+	s, err := New(e, nil, []Provider{&resource}, WithoutMigrations())
+	assert.NoError(t, err)
+	doc := s.OpenAPI(openapi.Info{Title: "test", Version: "1"})
+
+	assert.Equal(t, openapi.Version, doc.OpenAPI)
+	assert.Len(t, doc.Paths, 2)
+
+	list := doc.Paths["/posts"].Get
+	assert.Equal(t, "Every post", list.Summary)
+	assert.Equal(t, []string{"blog"}, list.Tags)
+	assert.Equal(t, "listPosts", list.OperationID)
+	assert.Nil(t, doc.Paths["/posts"].Post)
+
+	write := doc.Paths["/posts/{id}"].Put
+	assert.Equal(t, "id", write.Parameters[0].Name)
+	assert.Equal(t, openapi.Ref("testOpenAPIWrite"), write.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Contains(t, write.Responses, "404")
+
+	model := doc.Components.Schemas["testOpenAPIModel"]
+	assert.Contains(t, model.Properties, "ID")
+	assert.Contains(t, model.Properties, "Created")
+	assert.Contains(t, model.Properties, "title")
+	assert.NotContains(t, model.Properties, "internal")
+	assert.Equal(t, "date-time", model.Properties["Created"].Format)
+	assert.Equal(t, []string{"string", "null"}, model.Properties["Body"].Type)
+
+	writeSchema := doc.Components.Schemas["testOpenAPIWrite"]
+	assert.Equal(t, []string{"title"}, writeSchema.Required)
+
+	s.ServeOpenAPI("/openapi.json", openapi.Info{Title: "test", Version: "1"})
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var served map[string]any
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &served))
+	assert.Equal(t, openapi.Version, served["openapi"])
+}
+
+func TestServer_ServeOpenAPI_BasePath(t *testing.T) {
+	resource := FromModel[testOpenAPIModel]("posts", nil, NewPolicy[testOpenAPIModel](endpoints.AllEndpoints))
+
+	e := echo.New()
+	s, err := New(e, nil, []Provider{&resource}, WithoutMigrations(), WithBasePath("/api"))
+	assert.NoError(t, err)
+	s.ServeOpenAPI("/openapi.json", openapi.Info{Title: "test", Version: "1"})
+
+	for path, code := range map[string]int{"/api/openapi.json": http.StatusOK, "/openapi.json": http.StatusNotFound} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, code, rec.Code, path)
+	}
+}
@@ -0,0 +1,228 @@
+package sas
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/imthatgin/sas/pkg/migration"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/gommon/log"
+)
+
+// Logger is used by sas to report handled errors and failed migrations.
+// The *log.Logger of gommon, which echo uses, satisfies it.
+type Logger interface {
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+type gommonLogger struct{}
+
+func (gommonLogger) Infof(format string, args ...interface{})  { log.Infof(format, args...) }
+func (gommonLogger) Warnf(format string, args ...interface{})  { log.Warnf(format, args...) }
+func (gommonLogger) Errorf(format string, args ...interface{}) { log.Errorf(format, args...) }
+
+// orGlobalLogger returns logger, or the global logger of gommon when it is nil.
+func orGlobalLogger(logger Logger) Logger {
+	if logger == nil {
+		return gommonLogger{}
+	}
+
+	return logger
+}
+
+type config struct {
+	basePath string
+
+	migrator        *migration.Migrator
+	migrationsFatal bool
+
+	errorHandler     echo.HTTPErrorHandler
+	keepErrorHandler bool
+	logger           Logger
+	middlewares      []echo.MiddlewareFunc
+	jsonSerializer   echo.JSONSerializer
+
+	defaultPageSize int
+	maxPageSize     int
+
+	versionHeader  string
+	defaultVersion string
+
+	auditor *Auditor
+
+	webhooks *Webhooks
+	outbox   *Outbox
+
+	tenancy        *Tenancy
+	authenticators []Authenticator
+	decisions      *DecisionLog
+}
+
+// serverOptionTarget is implemented by resources which take the server wide options of New, such as
+// ModelResource.
+type serverOptionTarget interface {
+	// applyServerOptions applies the options the resource has no own value for, and returns an error for the
+	// options it can not be combined with.
+	applyServerOptions(c *config) error
+	RegisterHistoryMigration(m *migration.Migrator)
+}
+
+func (mr *ModelResource[T]) applyServerOptions(c *config) error {
+	mr.auditByDefault(c.auditor)
+	mr.webhooksByDefault(c.webhooks)
+	mr.outboxByDefault(c.outbox)
+	mr.decisionsByDefault(c.decisions)
+	mr.tenancyByDefault(c.tenancy)
+
+	return mr.checkTenantDatabases()
+}
+
+// Option configures a Server created with New.
+type Option func(c *config)
+
+func newConfig(options []Option) config {
+	c := config{
+		migrator:        migration.Default,
+		migrationsFatal: true,
+		logger:          gommonLogger{},
+	}
+
+	for _, option := range options {
+		option(&c)
+	}
+
+	// The error handler is created last, so it uses the configured logger.
+	if c.errorHandler == nil && !c.keepErrorHandler {
+		c.errorHandler = NewErrorHandler(c.logger)
+	}
+
+	return c
+}
+
+// WithBasePath registers every resource under prefix, such as /api/v1.
+func WithBasePath(prefix string) Option {
+	return func(c *config) {
+		prefix = strings.Trim(prefix, "/")
+		if prefix != "" {
+			prefix = "/" + prefix
+		}
+		c.basePath = prefix
+	}
+}
+
+// WithMigrations runs the migrations of migrator, instead of those of migration.Default.
+// When fatal is true New returns an error if they fail, otherwise the failure is only logged.
+func WithMigrations(migrator *migration.Migrator, fatal bool) Option {
+	return func(c *config) {
+		c.migrator = migrator
+		c.migrationsFatal = fatal
+	}
+}
+
+// WithoutMigrations leaves the database as is, for when migrations are applied separately.
+func WithoutMigrations() Option {
+	return WithMigrations(nil, false)
+}
+
+// WithErrorHandler replaces the error handler installed on echo, which is the one returned by NewErrorHandler.
+// A nil handler leaves the error handler of echo as is.
+func WithErrorHandler(handler echo.HTTPErrorHandler) Option {
+	return func(c *config) {
+		c.errorHandler = handler
+		c.keepErrorHandler = handler == nil
+	}
+}
+
+// WithLogger sets the logger used by sas, instead of the global logger of gommon.
+func WithLogger(logger Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// WithMiddlewares adds middlewares to every route of every resource, before the middlewares of the resource itself.
+func WithMiddlewares(middlewares ...echo.MiddlewareFunc) Option {
+	return func(c *config) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
+// WithPageSize limits the number of entities returned by list endpoints which use Paginate.
+// defaultSize applies when the request has no limit, and requests for more than maxSize are capped.
+// Zero disables either limit.
+func WithPageSize(defaultSize int, maxSize int) Option {
+	return func(c *config) {
+		c.defaultPageSize = defaultSize
+		c.maxPageSize = maxSize
+	}
+}
+
+// WithJSONSerializer sets the serializer echo uses to encode and decode JSON.
+func WithJSONSerializer(serializer echo.JSONSerializer) Option {
+	return func(c *config) {
+		c.jsonSerializer = serializer
+	}
+}
+
+// WithVersionHeader lets clients select the Version of a resource with a header, such as Accept-Version,
+// when the path has no version. defaultVersion is used when the header is absent, and may be empty to
+// only serve versioned paths in that case.
+func WithVersionHeader(header string, defaultVersion string) Option {
+	return func(c *config) {
+		c.versionHeader = http.CanonicalHeaderKey(header)
+		c.defaultVersion = strings.Trim(defaultVersion, "/")
+	}
+}
+
+// WithAuditor audits every resource which has no auditor of its own, and adds the migration of the audit table
+// to the migrations New runs.
+func WithAuditor(auditor *Auditor) Option {
+	return func(c *config) {
+		c.auditor = auditor
+	}
+}
+
+// WithWebhooks sends the events of every resource which has no webhooks of its own to webhooks, and adds the
+// migration of the webhook tables to the migrations New runs. The worker is started with Webhooks.Start.
+func WithWebhooks(webhooks *Webhooks) Option {
+	return func(c *config) {
+		c.webhooks = webhooks
+	}
+}
+
+// WithOutbox writes the events of every resource which has no outbox of its own to outbox, and adds the migration
+// of the outbox table to the migrations New runs. The outbox is drained by the worker started with Outbox.Start.
+func WithOutbox(outbox *Outbox) Option {
+	return func(c *config) {
+		c.outbox = outbox
+	}
+}
+
+// WithTenancy resolves the tenant of every request to the resources of models embedding TenantModel, which have no
+// tenancy of their own. Other resources are served without a tenant. When tenancy has databases but no migrations,
+// the migrations of the server are run on the database of each tenant.
+func WithTenancy(tenancy *Tenancy) Option {
+	return func(c *config) {
+		c.tenancy = tenancy
+	}
+}
+
+// WithAuthenticators sets the principal of every request to the resources, with the first authenticator which
+// handles its credentials. Requests without credentials stay anonymous, and invalid credentials fail with
+// ErrorUnauthenticated. The migrations of authenticators which have them, such as APIKeys, are added to the
+// migrations New runs.
+func WithAuthenticators(authenticators ...Authenticator) Option {
+	return func(c *config) {
+		c.authenticators = append(c.authenticators, authenticators...)
+	}
+}
+
+// WithDecisionLog records the decisions of the policies of every resource which has no decision log of its own in
+// log. ServeDecisions serves it.
+func WithDecisionLog(log *DecisionLog) Option {
+	return func(c *config) {
+		c.decisions = log
+	}
+}
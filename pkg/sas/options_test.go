@@ -0,0 +1,132 @@
+package sas
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/imthatgin/sas/pkg/migration"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestNew_Options(t *testing.T) {
+	db := newTestOptionsDB(t)
+	assert.NoError(t, db.AutoMigrate(&testOptionsModel{}))
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, db.Create(&testOptionsModel{Text: "entry"}).Error)
+	}
+
+	policy := NewPolicy[testOptionsModel](endpoints.GET)
+	policy.CanListAll(func(c Context) bool {
+		return true
+	})
+	resource := FromModel[testOptionsModel]("entries", db, policy)
+
+	e := echo.New()
+	var seen []string
+	s, err := New(e, db, []Provider{&resource},
+		WithoutMigrations(),
+		WithBasePath("api/v1/"),
+		WithPageSize(2, 3),
+		WithMiddlewares(func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				seen = append(seen, c.Path())
+				return next(c)
+			}
+		}),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "/api/v1", s.BasePath())
+	assert.Equal(t, "/api/v1/entries", s.Resources()[0].Routes[0].Path)
+
+	list := func(target string) int {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var result []testOptionsModel
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+		return len(result)
+	}
+
+	assert.Equal(t, 2, list("/api/v1/entries"))
+	assert.Equal(t, 3, list("/api/v1/entries?limit=10"))
+	assert.Equal(t, 1, list("/api/v1/entries?limit=1"))
+	assert.Equal(t, []string{"/api/v1/entries", "/api/v1/entries", "/api/v1/entries"}, seen)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/entries", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestNew_MigrationFailure(t *testing.T) {
+	failing := func() *migration.Migrator {
+		m := migration.NewMigrator()
+		m.Add(migration.Migration{
+			Name:       "001_broken",
+			NoChecksum: true,
+			Up: func(db *gorm.DB) error {
+				return errors.New("broken")
+			},
+		})
+		return m
+	}
+
+	e := echo.New()
+	s, err := New(e, newTestOptionsDB(t), nil, WithMigrations(failing(), true))
+	assert.ErrorIs(t, err, ErrorMigrationsFailed)
+	assert.Nil(t, s)
+
+	s, err = New(e, newTestOptionsDB(t), nil, WithMigrations(failing(), false))
+	assert.NoError(t, err)
+	assert.NotNil(t, s)
+}
+
+func TestNew_ErrorHandler(t *testing.T) {
+	e := echo.New()
+	called := false
+	e.HTTPErrorHandler = func(err error, c echo.Context) {
+		called = true
+	}
+
+	_, err := New(e, nil, nil, WithoutMigrations(), WithErrorHandler(nil))
+	assert.NoError(t, err)
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	assert.True(t, called)
+
+	_, err = New(e, nil, nil, WithoutMigrations())
+	assert.NoError(t, err)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+type testLogger struct {
+	name string
+}
+
+func (l *testLogger) Infof(format string, args ...interface{})  {}
+func (l *testLogger) Warnf(format string, args ...interface{})  {}
+func (l *testLogger) Errorf(format string, args ...interface{}) {}
+
+func TestNew_Logger(t *testing.T) {
+	logger := &testLogger{name: "server"}
+	own := &testLogger{name: "own"}
+	webhooks := NewWebhooks(nil, WebhookOptions{})
+	outbox := NewOutbox(nil, nil, OutboxOptions{})
+	ownWebhooks := NewWebhooks(nil, WebhookOptions{}).Logger(own)
+
+	_, err := New(echo.New(), nil, nil, WithoutMigrations(), WithLogger(logger), WithWebhooks(webhooks), WithOutbox(outbox))
+	assert.NoError(t, err)
+	assert.Same(t, logger, webhooks.logger)
+	assert.Same(t, logger, outbox.logger)
+
+	_, err = New(echo.New(), nil, nil, WithoutMigrations(), WithLogger(logger), WithWebhooks(ownWebhooks))
+	assert.NoError(t, err)
+	assert.Same(t, own, ownWebhooks.logger)
+}
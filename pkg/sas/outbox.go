@@ -0,0 +1,306 @@
+package sas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/imthatgin/sas/pkg/migration"
+	"gorm.io/gorm"
+)
+
+const (
+	// OutboxTableName is the table outbox messages are written to.
+	OutboxTableName = "__sas_outbox"
+
+	// OutboxMigrationName is the name of the system migration which creates the outbox table.
+	OutboxMigrationName = "000_sas_outbox"
+)
+
+// OutboxMessage is an event written in the same transaction as its change, which stays in the outbox until
+// a relay has delivered it. Consumers should deduplicate on ID, as a message can be delivered more than once.
+type OutboxMessage struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	Resource string          `gorm:"size:255" json:"resource"`
+	Type     EventType       `gorm:"size:32" json:"type"`
+	EntityID uint            `json:"entityId"`
+	Tenant   string          `gorm:"size:255" json:"tenant,omitempty"`
+	Data     json.RawMessage `json:"data"`
+
+	CreatedAt   time.Time  `json:"createdAt"`
+	DeliveredAt *time.Time `gorm:"index" json:"deliveredAt,omitempty"`
+	Attempts    int        `json:"-"`
+	LastError   string     `json:"-"`
+}
+
+func (OutboxMessage) TableName() string {
+	return OutboxTableName
+}
+
+// Relay delivers the messages of an outbox, such as to a message broker. A message is only marked delivered
+// when Deliver returns nil, and is retried otherwise.
+type Relay interface {
+	Deliver(ctx context.Context, message OutboxMessage) error
+}
+
+// RelayFunc adapts a function to Relay.
+type RelayFunc func(ctx context.Context, message OutboxMessage) error
+
+func (f RelayFunc) Deliver(ctx context.Context, message OutboxMessage) error {
+	return f(ctx, message)
+}
+
+// ChannelRelay delivers messages to an in-process channel, blocking until they are received.
+type ChannelRelay chan OutboxMessage
+
+func (r ChannelRelay) Deliver(ctx context.Context, message OutboxMessage) error {
+	select {
+	case r <- message:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WriterRelay writes each message as a line of JSON, such as to a log file.
+type WriterRelay struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterRelay creates a relay writing to w.
+func NewWriterRelay(w io.Writer) *WriterRelay {
+	return &WriterRelay{w: w}
+}
+
+func (r *WriterRelay) Deliver(ctx context.Context, message OutboxMessage) error {
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, err = r.w.Write(append(encoded, '\n'))
+	return err
+}
+
+// HTTPRelay posts each message as JSON to URL, which has to respond with a 2xx status.
+// The ID of the message is sent in the X-Sas-Outbox-ID header.
+type HTTPRelay struct {
+	URL    string
+	Client *http.Client
+	Header http.Header
+}
+
+func (r *HTTPRelay) Deliver(ctx context.Context, message OutboxMessage) error {
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	for key, values := range r.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sas-Outbox-ID", strconv.FormatUint(uint64(message.ID), 10))
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("relay responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// OutboxOptions configures the draining of an outbox. Zero fields use the value of DefaultOutboxOptions.
+type OutboxOptions struct {
+	// PollInterval is how often the outbox is drained, besides after each committed change.
+	PollInterval time.Duration
+	// BatchSize is the number of messages delivered per drain.
+	BatchSize int
+}
+
+// DefaultOutboxOptions are used for the zero fields of the options given to NewOutbox.
+var DefaultOutboxOptions = OutboxOptions{
+	PollInterval: 5 * time.Second,
+	BatchSize:    100,
+}
+
+// Outbox writes the events of resources to the outbox table in the transaction of their change, and drains
+// them through a relay. Messages are relayed in the order they were written, and a failed message holds back
+// the messages after it until it is delivered.
+type Outbox struct {
+	db      *gorm.DB
+	relay   Relay
+	options OutboxOptions
+	logger  Logger
+
+	wake chan struct{}
+}
+
+// NewOutbox creates an outbox stored in db, and drained through relay.
+func NewOutbox(db *gorm.DB, relay Relay, options OutboxOptions) *Outbox {
+	if options.PollInterval <= 0 {
+		options.PollInterval = DefaultOutboxOptions.PollInterval
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultOutboxOptions.BatchSize
+	}
+
+	return &Outbox{
+		db:      db,
+		relay:   relay,
+		options: options,
+
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Logger sets the logger the worker reports errors to. New sets the logger of the server on the outbox given
+// to WithOutbox, unless it has its own.
+func (o *Outbox) Logger(logger Logger) *Outbox {
+	o.logger = logger
+	return o
+}
+
+// RegisterMigration adds the system migration which creates the outbox table to m, unless it already has it.
+func (o *Outbox) RegisterMigration(m *migration.Migrator) {
+	for _, existing := range m.Migrations() {
+		if existing.Name == OutboxMigrationName {
+			return
+		}
+	}
+
+	m.RegisterSystemMigration(OutboxMigrationName, func(db *gorm.DB) error {
+		return db.AutoMigrate(&OutboxMessage{})
+	}, func(db *gorm.DB) error {
+		return db.Migrator().DropTable(&OutboxMessage{})
+	})
+}
+
+// Start runs the worker which drains the outbox, until ctx is done.
+func (o *Outbox) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(o.options.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := o.Drain(ctx); err != nil {
+				orGlobalLogger(o.logger).Errorf("Could not drain the outbox: %s", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-o.wake:
+			}
+		}
+	}()
+}
+
+// Drain delivers the undelivered messages through the relay, up to the batch size, and returns how many were
+// delivered. It stops at the first message the relay fails to deliver. It is called by the worker, and can be
+// called directly instead of starting it.
+func (o *Outbox) Drain(ctx context.Context) (int, error) {
+	var messages []OutboxMessage
+	err := o.db.WithContext(ctx).
+		Where("delivered_at IS NULL").
+		Order("id").
+		Limit(o.options.BatchSize).
+		Find(&messages).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for i, message := range messages {
+		if err := o.relay.Deliver(ctx, message); err != nil {
+			updateErr := o.db.Model(&message).Updates(map[string]any{
+				"attempts":   message.Attempts + 1,
+				"last_error": err.Error(),
+			}).Error
+			if updateErr != nil {
+				return i, updateErr
+			}
+
+			return i, err
+		}
+
+		if err := o.db.Model(&message).Update("delivered_at", time.Now().UTC()).Error; err != nil {
+			return i, err
+		}
+	}
+
+	return len(messages), nil
+}
+
+// write adds the event to the outbox, inside the transaction of its change.
+func (o *Outbox) write(tx *gorm.DB, event Event) error {
+	return tx.Create(&OutboxMessage{
+		Resource:  event.Resource,
+		Type:      event.Type,
+		EntityID:  event.EntityID,
+		Tenant:    event.Tenant,
+		Data:      event.Data,
+		CreatedAt: event.Timestamp,
+	}).Error
+}
+
+// notify wakes the worker, once a change with outbox messages has been committed.
+func (o *Outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Outbox writes the events of the creates, writes and deletes of the resource to outbox, in the transaction of
+// the change. The outbox table is created by the migration added with Outbox.RegisterMigration, or by WithOutbox.
+func (mr *ModelResource[T]) Outbox(outbox *Outbox) {
+	mr.outbox = outbox
+}
+
+// outboxByDefault enables outbox for the resource, unless it has its own.
+func (mr *ModelResource[T]) outboxByDefault(outbox *Outbox) {
+	if mr.outbox == nil {
+		mr.outbox = outbox
+	}
+}
+
+// writeOutbox writes the event of a change to the outbox of the resource, if it has one.
+func (mr *ModelResource[T]) writeOutbox(c Context, tx *gorm.DB, action Operation, before *T, after *T) error {
+	if mr.outbox == nil {
+		return nil
+	}
+
+	event, ok := mr.newEvent(c, action, before, after)
+	if !ok {
+		return nil
+	}
+
+	return mr.outbox.write(tx, event)
+}
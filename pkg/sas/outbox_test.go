@@ -0,0 +1,83 @@
+package sas
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/imthatgin/sas/pkg/migration"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutbox(t *testing.T) {
+	db, resource := newTestEntries(t, endpoints.AllEndpoints|endpoints.BULK)
+
+	var relayed []OutboxMessage
+	fail := false
+	outbox := NewOutbox(db, RelayFunc(func(ctx context.Context, message OutboxMessage) error {
+		if fail {
+			return errors.New("relay is down")
+		}
+		relayed = append(relayed, message)
+		return nil
+	}), OutboxOptions{})
+
+	s := newTestServer(t, db, []Provider{&resource}, WithMigrations(migration.NewMigrator(), true), WithOutbox(outbox))
+
+	assert.Equal(t, http.StatusOK, s.serve(http.MethodPost, "/entries", `{"Text":"first"}`).Code)
+	assert.Equal(t, http.StatusOK, s.serve(http.MethodPut, "/entries/1", `{"Text":"second"}`).Code)
+
+	// The message of a change which is rolled back is rolled back with it.
+	assert.Equal(t, http.StatusNotFound, s.serve(http.MethodDelete, "/entries?ids=1,2", "").Code)
+
+	var count int64
+	assert.NoError(t, db.Model(&OutboxMessage{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+
+	fail = true
+	delivered, err := outbox.Drain(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 0, delivered)
+
+	fail = false
+	delivered, err = outbox.Drain(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, delivered)
+	assert.Equal(t, []EventType{EventCreated, EventUpdated}, []EventType{relayed[0].Type, relayed[1].Type})
+	assert.Contains(t, string(relayed[1].Data), `"Text":"second"`)
+
+	delivered, err = outbox.Drain(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+}
+
+func TestOutboxRelays(t *testing.T) {
+	message := OutboxMessage{ID: 7, Resource: "entries", Type: EventDeleted, EntityID: 3, Data: []byte(`{}`)}
+
+	var buffer bytes.Buffer
+	assert.NoError(t, NewWriterRelay(&buffer).Deliver(context.Background(), message))
+	assert.Contains(t, buffer.String(), `"id":7,"resource":"entries","type":"deleted"`)
+	assert.True(t, strings.HasSuffix(buffer.String(), "}\n"))
+
+	var outboxID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outboxID = r.Header.Get("X-Sas-Outbox-ID")
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	assert.NoError(t, (&HTTPRelay{URL: server.URL}).Deliver(context.Background(), message))
+	assert.Equal(t, "7", outboxID)
+	assert.Error(t, (&HTTPRelay{URL: server.URL + "/fail"}).Deliver(context.Background(), message))
+
+	channel := make(ChannelRelay, 1)
+	assert.NoError(t, channel.Deliver(context.Background(), message))
+	assert.Equal(t, message.ID, (<-channel).ID)
+}
@@ -1,75 +1,245 @@
 package sas
 
 import (
+	"slices"
+
 	"github.com/imthatgin/sas/pkg/endpoints"
-	"github.com/labstack/echo/v4"
 )
 
 // Policy represents the permission requirements and endpoints enabled for a given model.
 type Policy[T any] struct {
 	EnabledEndpoints endpoints.HttpEndpointType
 
-	canListAll    func(c echo.Context) bool
-	canListById   func(c echo.Context, entity T) bool
-	canWriteById  func(c echo.Context, entity T) bool
-	canCreate     func(c echo.Context) bool
-	canDeleteById func(c echo.Context, entity T) bool
+	listAll    func(c Context) Decision
+	listById   func(c Context, entity T) Decision
+	writeById  func(c Context, entity T) Decision
+	create     func(c Context) Decision
+	deleteById func(c Context, entity T) Decision
+
+	listHistory func(c Context, entity T) Decision
+	revertById  func(c Context, entity T) Decision
+
+	// createEntity and writeChange check the payload of creates and writes once it is bound, after create and
+	// writeById allowed the operation. Every payload is allowed while they are nil.
+	createEntity func(c Context, entity T) Decision
+	writeChange  func(c Context, old T, new T) Decision
+
+	// customized holds the names of the predicates which have been set, and no longer apply their default.
+	customized []string
+}
+
+// PredicateDescription reports whether a policy predicate has been set, or still applies its default.
+// Default is what the predicate decides while it is not set: the operations deny, and the payload checks
+// CanCreateEntity and CanWriteChange allow.
+type PredicateDescription struct {
+	Name       string `json:"name"`
+	Customized bool   `json:"customized"`
+	Default    Effect `json:"default"`
+}
+
+// Predicates lists every predicate of the policy, whether it has been customized, and its default.
+func (p *Policy[T]) Predicates() []PredicateDescription {
+	var predicates []PredicateDescription
+	for _, name := range []string{"CanListAll", "CanListById", "CanWriteById", "CanCreate", "CanDeleteById", "CanListHistory", "CanRevertById", "CanCreateEntity", "CanWriteChange"} {
+		fallback := EffectDeny
+		if name == "CanCreateEntity" || name == "CanWriteChange" {
+			fallback = EffectAllow
+		}
+
+		predicates = append(predicates, PredicateDescription{
+			Name:       name,
+			Customized: slices.Contains(p.customized, name),
+			Default:    fallback,
+		})
+	}
+
+	return predicates
+}
+
+func (p *Policy[T]) customize(name string) {
+	if !slices.Contains(p.customized, name) {
+		p.customized = append(p.customized, name)
+	}
 }
 
-// NewPolicy creates a default policy instance, which will deny all operations by default.
-// Use the exposed methods on the Policy type to set the predicates.
+// NewPolicy creates a default policy instance, which will deny all operations by default, and allow every
+// payload of the operations it allows. Use the exposed methods on the Policy type to set the predicates.
 func NewPolicy[T any](endpoints endpoints.HttpEndpointType) Policy[T] {
 	return Policy[T]{
 		EnabledEndpoints: endpoints,
 
-		canListAll: func(c echo.Context) bool {
-			return false
-		},
+		listAll:    func(c Context) Decision { return notSet("CanListAll") },
+		listById:   func(c Context, entity T) Decision { return notSet("CanListById") },
+		writeById:  func(c Context, entity T) Decision { return notSet("CanWriteById") },
+		create:     func(c Context) Decision { return notSet("CanCreate") },
+		deleteById: func(c Context, entity T) Decision { return notSet("CanDeleteById") },
+
+		listHistory: func(c Context, entity T) Decision { return notSet("CanListHistory") },
+		revertById:  func(c Context, entity T) Decision { return notSet("CanRevertById") },
+	}
+}
+
+// CanListAll takes a predicate and determines whether the operation can proceed.
+func (p *Policy[T]) CanListAll(predicate func(c Context) bool) *Policy[T] {
+	return p.DecideListAll(func(c Context) Decision {
+		return predicateDecision("CanListAll", predicate(c))
+	})
+}
 
-		canListById: func(c echo.Context, entity T) bool {
-			return false
-		},
+// CanListById takes a predicate and determines whether the operation can proceed.
+func (p *Policy[T]) CanListById(predicate func(c Context, entity T) bool) *Policy[T] {
+	return p.DecideListById(func(c Context, entity T) Decision {
+		return predicateDecision("CanListById", predicate(c, entity))
+	})
+}
 
-		canWriteById: func(c echo.Context, entity T) bool {
-			return false
-		},
+// CanWriteById takes a predicate and determines whether the operation can proceed.
+func (p *Policy[T]) CanWriteById(predicate func(c Context, entity T) bool) *Policy[T] {
+	return p.DecideWriteById(func(c Context, entity T) Decision {
+		return predicateDecision("CanWriteById", predicate(c, entity))
+	})
+}
 
-		canCreate: func(c echo.Context) bool {
-			return false
-		},
+// CanDeleteById takes a predicate and determines whether the operation can proceed.
+func (p *Policy[T]) CanDeleteById(predicate func(c Context, entity T) bool) *Policy[T] {
+	return p.DecideDeleteById(func(c Context, entity T) Decision {
+		return predicateDecision("CanDeleteById", predicate(c, entity))
+	})
+}
 
-		canDeleteById: func(c echo.Context, entity T) bool {
-			return false
-		},
-	}
+// CanCreate takes a predicate and determines whether the operation can proceed.
+func (p *Policy[T]) CanCreate(predicate func(c Context) bool) *Policy[T] {
+	return p.DecideCreate(func(c Context) Decision {
+		return predicateDecision("CanCreate", predicate(c))
+	})
 }
 
-// CanListAll takes a predicate and determines whether the operation can proceed.
-func (p *Policy[T]) CanListAll(predicate func(c echo.Context) bool) *Policy[T] {
-	p.canListAll = predicate
+// CanListHistory takes a predicate and determines whether the prior versions of an entity can be read.
+func (p *Policy[T]) CanListHistory(predicate func(c Context, entity T) bool) *Policy[T] {
+	return p.DecideListHistory(func(c Context, entity T) Decision {
+		return predicateDecision("CanListHistory", predicate(c, entity))
+	})
+}
+
+// CanRevertById takes a predicate and determines whether an entity can be reverted to a prior version.
+func (p *Policy[T]) CanRevertById(predicate func(c Context, entity T) bool) *Policy[T] {
+	return p.DecideRevertById(func(c Context, entity T) Decision {
+		return predicateDecision("CanRevertById", predicate(c, entity))
+	})
+}
+
+// CanCreateEntity takes a predicate and determines whether the entity bound from the request can be created.
+// It is checked for each entity after CanCreate allowed the operation, and allows every entity until it is set.
+func (p *Policy[T]) CanCreateEntity(predicate func(c Context, entity T) bool) *Policy[T] {
+	return p.DecideCreateEntity(func(c Context, entity T) Decision {
+		return predicateDecision("CanCreateEntity", predicate(c, entity))
+	})
+}
+
+// CanWriteChange takes a predicate and determines whether an entity can be changed from old to new, which is old
+// with the changes of the request applied. It is checked after CanWriteById allowed the operation, and allows
+// every change until it is set.
+func (p *Policy[T]) CanWriteChange(predicate func(c Context, old T, new T) bool) *Policy[T] {
+	return p.DecideWriteChange(func(c Context, old T, new T) Decision {
+		return predicateDecision("CanWriteChange", predicate(c, old, new))
+	})
+}
+
+// DecideListAll sets the predicate of CanListAll to one which explains its decision.
+func (p *Policy[T]) DecideListAll(predicate func(c Context) Decision) *Policy[T] {
+	p.listAll = predicate
+	p.customize("CanListAll")
 	return p
 }
 
-// CanListById takes a predicate and determines whether the operation can proceed.
-func (p *Policy[T]) CanListById(predicate func(c echo.Context, entity T) bool) *Policy[T] {
-	p.canListById = predicate
+// DecideListById sets the predicate of CanListById to one which explains its decision.
+func (p *Policy[T]) DecideListById(predicate func(c Context, entity T) Decision) *Policy[T] {
+	p.listById = predicate
+	p.customize("CanListById")
 	return p
 }
 
-// CanWriteById takes a predicate and determines whether the operation can proceed.
-func (p *Policy[T]) CanWriteById(predicate func(c echo.Context, entity T) bool) *Policy[T] {
-	p.canWriteById = predicate
+// DecideWriteById sets the predicate of CanWriteById to one which explains its decision.
+func (p *Policy[T]) DecideWriteById(predicate func(c Context, entity T) Decision) *Policy[T] {
+	p.writeById = predicate
+	p.customize("CanWriteById")
 	return p
 }
 
-// CanDeleteById takes a predicate and determines whether the operation can proceed.
-func (p *Policy[T]) CanDeleteById(predicate func(c echo.Context, entity T) bool) *Policy[T] {
-	p.canDeleteById = predicate
+// DecideDeleteById sets the predicate of CanDeleteById to one which explains its decision.
+func (p *Policy[T]) DecideDeleteById(predicate func(c Context, entity T) Decision) *Policy[T] {
+	p.deleteById = predicate
+	p.customize("CanDeleteById")
 	return p
 }
 
-// CanCreate takes a predicate and determines whether the operation can proceed.
-func (p *Policy[T]) CanCreate(predicate func(c echo.Context) bool) *Policy[T] {
-	p.canCreate = predicate
+// DecideCreate sets the predicate of CanCreate to one which explains its decision.
+func (p *Policy[T]) DecideCreate(predicate func(c Context) Decision) *Policy[T] {
+	p.create = predicate
+	p.customize("CanCreate")
+	return p
+}
+
+// DecideListHistory sets the predicate of CanListHistory to one which explains its decision.
+func (p *Policy[T]) DecideListHistory(predicate func(c Context, entity T) Decision) *Policy[T] {
+	p.listHistory = predicate
+	p.customize("CanListHistory")
+	return p
+}
+
+// DecideRevertById sets the predicate of CanRevertById to one which explains its decision.
+func (p *Policy[T]) DecideRevertById(predicate func(c Context, entity T) Decision) *Policy[T] {
+	p.revertById = predicate
+	p.customize("CanRevertById")
+	return p
+}
+
+// DecideCreateEntity sets the predicate of CanCreateEntity to one which explains its decision.
+func (p *Policy[T]) DecideCreateEntity(predicate func(c Context, entity T) Decision) *Policy[T] {
+	p.createEntity = predicate
+	p.customize("CanCreateEntity")
 	return p
 }
+
+// DecideWriteChange sets the predicate of CanWriteChange to one which explains its decision.
+func (p *Policy[T]) DecideWriteChange(predicate func(c Context, old T, new T) Decision) *Policy[T] {
+	p.writeChange = predicate
+	p.customize("CanWriteChange")
+	return p
+}
+
+func (p *Policy[T]) canListAll(c Context) bool {
+	return p.listAll(c).Allowed
+}
+
+func (p *Policy[T]) canListById(c Context, entity T) bool {
+	return p.listById(c, entity).Allowed
+}
+
+func (p *Policy[T]) canWriteById(c Context, entity T) bool {
+	return p.writeById(c, entity).Allowed
+}
+
+func (p *Policy[T]) canCreate(c Context) bool {
+	return p.create(c).Allowed
+}
+
+func (p *Policy[T]) canDeleteById(c Context, entity T) bool {
+	return p.deleteById(c, entity).Allowed
+}
+
+func (p *Policy[T]) canListHistory(c Context, entity T) bool {
+	return p.listHistory(c, entity).Allowed
+}
+
+func (p *Policy[T]) canRevertById(c Context, entity T) bool {
+	return p.revertById(c, entity).Allowed
+}
+
+func (p *Policy[T]) canCreateEntity(c Context, entity T) bool {
+	return p.createEntity == nil || p.createEntity(c, entity).Allowed
+}
+
+func (p *Policy[T]) canWriteChange(c Context, old T, new T) bool {
+	return p.writeChange == nil || p.writeChange(c, old, new).Allowed
+}
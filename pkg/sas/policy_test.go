@@ -1,6 +1,10 @@
 package sas
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/imthatgin/sas/pkg/endpoints"
@@ -29,7 +33,7 @@ func TestPolicy_CanDeleteById(t *testing.T) {
 
 	assert.Equal(t, false, policy.canDeleteById(ctx, testPolicyModel{}))
 
-	policy.CanDeleteById(func(c echo.Context, entity testPolicyModel) bool {
+	policy.CanDeleteById(func(c Context, entity testPolicyModel) bool {
 		return true
 	})
 
@@ -43,7 +47,7 @@ func TestPolicy_CanListAll(t *testing.T) {
 
 	assert.Equal(t, false, policy.canListAll(ctx))
 
-	policy.CanListAll(func(c echo.Context) bool {
+	policy.CanListAll(func(c Context) bool {
 		return true
 	})
 
@@ -57,7 +61,7 @@ func TestPolicy_CanListById(t *testing.T) {
 
 	assert.Equal(t, false, policy.canListById(ctx, testPolicyModel{}))
 
-	policy.CanListById(func(c echo.Context, entity testPolicyModel) bool {
+	policy.CanListById(func(c Context, entity testPolicyModel) bool {
 		return true
 	})
 
@@ -71,7 +75,7 @@ func TestPolicy_CanWriteById(t *testing.T) {
 
 	assert.Equal(t, false, policy.canWriteById(ctx, testPolicyModel{}))
 
-	policy.CanWriteById(func(c echo.Context, entity testPolicyModel) bool {
+	policy.CanWriteById(func(c Context, entity testPolicyModel) bool {
 		return true
 	})
 
@@ -85,9 +89,138 @@ func TestPolicy_CanCreate(t *testing.T) {
 
 	assert.Equal(t, false, policy.canCreate(ctx))
 
-	policy.CanCreate(func(c echo.Context) bool {
+	policy.CanCreate(func(c Context) bool {
 		return true
 	})
 
 	assert.Equal(t, true, policy.canCreate(ctx))
 }
+
+func TestPolicy_CanCreateEntity(t *testing.T) {
+	policy := NewPolicy[testPolicyModel](endpoints.AllEndpoints)
+	e := echo.New()
+	ctx := e.NewContext(nil, nil)
+
+	assert.Equal(t, true, policy.canCreateEntity(ctx, testPolicyModel{}))
+
+	policy.CanCreateEntity(func(c Context, entity testPolicyModel) bool {
+		return false
+	})
+
+	assert.Equal(t, false, policy.canCreateEntity(ctx, testPolicyModel{}))
+}
+
+func TestPolicy_CanWriteChange(t *testing.T) {
+	policy := NewPolicy[testPolicyModel](endpoints.AllEndpoints)
+	e := echo.New()
+	ctx := e.NewContext(nil, nil)
+
+	assert.Equal(t, true, policy.canWriteChange(ctx, testPolicyModel{}, testPolicyModel{}))
+
+	policy.CanWriteChange(func(c Context, old testPolicyModel, new testPolicyModel) bool {
+		return false
+	})
+
+	assert.Equal(t, false, policy.canWriteChange(ctx, testPolicyModel{}, testPolicyModel{}))
+}
+
+// TestPolicy_Endpoints checks that each endpoint consults its own predicate. The predicates allow the operations
+// listed in the X-Allow header.
+func TestPolicy_Endpoints(t *testing.T) {
+	db := newTestOptionsDB(t)
+	assert.NoError(t, db.AutoMigrate(&testOptionsModel{}))
+
+	allows := func(c Context, operation string) bool {
+		return slices.Contains(strings.Split(c.Request().Header.Get("X-Allow"), ","), operation)
+	}
+
+	policy := NewPolicy[testOptionsModel](endpoints.AllEndpoints | endpoints.PATCH | endpoints.BULK)
+	policy.
+		CanListAll(func(c Context) bool { return allows(c, "listAll") }).
+		CanListById(func(c Context, entity testOptionsModel) bool { return allows(c, "listById") }).
+		CanWriteById(func(c Context, entity testOptionsModel) bool { return allows(c, "writeById") }).
+		CanCreate(func(c Context) bool { return allows(c, "create") }).
+		CanDeleteById(func(c Context, entity testOptionsModel) bool { return allows(c, "deleteById") }).
+		CanCreateEntity(func(c Context, entity testOptionsModel) bool { return entity.Text != "forbidden" }).
+		CanWriteChange(func(c Context, old testOptionsModel, new testOptionsModel) bool {
+			return old.Text != "locked" && new.Text != "forbidden"
+		})
+
+	resource := FromModel[testOptionsModel]("entries", db, policy)
+	resource.CreateBindType(testBulkWrite{})
+	resource.WriteBindType(testBulkWrite{})
+
+	e := echo.New()
+	_, err := New(e, db, []Provider{&resource}, WithoutMigrations())
+	assert.NoError(t, err)
+
+	serve := func(method string, target string, body string, allow string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Allow", allow)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	text := func(id uint) string {
+		var entity testOptionsModel
+		assert.NoError(t, db.First(&entity, id).Error)
+		return entity.Text
+	}
+
+	t.Run("create", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/entries", `{"Text":"a"}`, "listAll,writeById").Code)
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/entries", `{"Text":"forbidden"}`, "create").Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/entries", `{"Text":"first"}`, "create").Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/entries", `{"Text":"locked"}`, "create").Code)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/entries", "", "listById").Code)
+
+		rec := serve(http.MethodGet, "/entries", "", "listAll")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"Text":"first"`)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/entries/1", "", "listAll").Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/entries/1", "", "listById").Code)
+		assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/entries/9", "", "listById").Code)
+	})
+
+	t.Run("write", func(t *testing.T) {
+		// Reading an entity does not allow writing it.
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodPut, "/entries/1", `{"Text":"second"}`, "listById").Code)
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodPut, "/entries/1", `{"Text":"forbidden"}`, "writeById").Code)
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodPut, "/entries/2", `{"Text":"unlocked"}`, "writeById").Code)
+		assert.Equal(t, "first", text(1))
+		assert.Equal(t, "locked", text(2))
+
+		assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/entries/1", `{"Text":"second"}`, "writeById").Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/entries/1", `{"Text":"third"}`, "writeById").Code)
+		assert.Equal(t, "third", text(1))
+		assert.Equal(t, http.StatusNotFound, serve(http.MethodPut, "/entries/9", `{"Text":"second"}`, "writeById").Code)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodDelete, "/entries/1", "", "listById,writeById").Code)
+		assert.Equal(t, http.StatusNotFound, serve(http.MethodDelete, "/entries/9", "", "deleteById").Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodDelete, "/entries/1", "", "deleteById").Code)
+		assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/entries/1", "", "listById").Code)
+	})
+
+	t.Run("bulk", func(t *testing.T) {
+		rec := serve(http.MethodPost, "/entries/bulk?mode=partial", `[{"Text":"bulk"},{"Text":"forbidden"}]`, "create")
+		assert.Equal(t, http.StatusMultiStatus, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"status":403`)
+
+		// An atomic request has the status of the item which failed.
+		rec = serve(http.MethodPatch, "/entries/bulk", `[{"id":2,"changes":{"Text":"unlocked"}}]`, "writeById")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "locked", text(2))
+
+		rec = serve(http.MethodDelete, "/entries?ids=2", "", "listById,writeById")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
@@ -0,0 +1,204 @@
+package sas
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+const contextKeyPrincipal = "sas.principal"
+
+// ErrorUnauthenticated is returned for requests with invalid credentials, or without credentials where they
+// are required.
+var ErrorUnauthenticated = errors.New("request is not authenticated")
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	ID     string
+	Roles  []string
+	Scopes []string
+	Tenant string
+
+	// Claims holds every claim of the credentials, such as those of a JWT.
+	Claims map[string]any
+}
+
+// HasRole reports whether the principal has any of roles.
+func (p *Principal) HasRole(roles ...string) bool {
+	if p == nil {
+		return false
+	}
+
+	for _, role := range roles {
+		if slices.Contains(p.Roles, role) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasScope reports whether the principal has every one of scopes.
+func (p *Principal) HasScope(scopes ...string) bool {
+	if p == nil {
+		return false
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(p.Scopes, scope) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PrincipalFrom returns the principal of the request, or nil for anonymous requests.
+func PrincipalFrom(c Context) *Principal {
+	principal, _ := c.Get(contextKeyPrincipal).(*Principal)
+	return principal
+}
+
+// Authenticator reads the principal of a request from its credentials. It returns nil without an error when
+// the request has no credentials it handles, so the next authenticator can be tried.
+type Authenticator interface {
+	Authenticate(c Context) (*Principal, error)
+}
+
+// AuthenticatorFunc adapts a function to Authenticator.
+type AuthenticatorFunc func(c Context) (*Principal, error)
+
+func (f AuthenticatorFunc) Authenticate(c Context) (*Principal, error) {
+	return f(c)
+}
+
+// Authenticate sets the principal of the first authenticator which handles the credentials of the request.
+// Invalid credentials fail with ErrorUnauthenticated, as do requests without a principal when required is set.
+func Authenticate(required bool, authenticators ...Authenticator) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			for _, authenticator := range authenticators {
+				principal, err := authenticator.Authenticate(c)
+				if err != nil {
+					return errors.Join(ErrorUnauthenticated, err)
+				}
+
+				if principal != nil {
+					c.Set(contextKeyPrincipal, principal)
+					return next(c)
+				}
+			}
+
+			if required {
+				return ErrorUnauthenticated
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// StaticTokens authenticates bearer tokens with a fixed principal each, such as in tests. Bearer tokens which
+// are not in tokens fail with ErrorUnauthenticated, so it goes after the authenticators of other bearer tokens,
+// such as JWTAuthenticator.
+func StaticTokens(tokens map[string]*Principal) Authenticator {
+	return AuthenticatorFunc(func(c Context) (*Principal, error) {
+		token := bearerToken(c)
+		if token == "" {
+			return nil, nil
+		}
+
+		principal, ok := tokens[token]
+		if !ok || principal == nil {
+			return nil, errors.New("unknown token")
+		}
+
+		return principal, nil
+	})
+}
+
+// bearerToken returns the token of the Authorization header of the request, if it is a bearer token.
+func bearerToken(c Context) string {
+	scheme, token, ok := strings.Cut(c.Request().Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
+// Check is a condition on the principal of a request, which is nil for anonymous requests.
+type Check func(p *Principal) bool
+
+// IsAuthenticated allows every request with a principal.
+func IsAuthenticated() Check {
+	return func(p *Principal) bool {
+		return p != nil
+	}
+}
+
+// HasRole allows principals with any of roles.
+func HasRole(roles ...string) Check {
+	return func(p *Principal) bool {
+		return p.HasRole(roles...)
+	}
+}
+
+// HasScope allows principals with every one of scopes.
+func HasScope(scopes ...string) Check {
+	return func(p *Principal) bool {
+		return p.HasScope(scopes...)
+	}
+}
+
+// Permission is a set of operations a policy allows.
+type Permission int
+
+const (
+	ReadAll Permission = 1 << iota
+	ReadById
+	Write
+	Create
+	Delete
+	ListHistory
+	Revert
+
+	// Read allows listing all entities and each entity by ID.
+	Read = ReadAll | ReadById
+	// All allows every operation.
+	All = Read | Write | Create | Delete | ListHistory | Revert
+)
+
+// Allow sets the predicates of permissions to check the principal of the request with check.
+func (p *Policy[T]) Allow(permissions Permission, check Check) *Policy[T] {
+	allowed := func(c Context) bool {
+		return check(PrincipalFrom(c))
+	}
+	allowedFor := func(c Context, entity T) bool {
+		return allowed(c)
+	}
+
+	if permissions&ReadAll != 0 {
+		p.CanListAll(allowed)
+	}
+	if permissions&ReadById != 0 {
+		p.CanListById(allowedFor)
+	}
+	if permissions&Write != 0 {
+		p.CanWriteById(allowedFor)
+	}
+	if permissions&Create != 0 {
+		p.CanCreate(allowed)
+	}
+	if permissions&Delete != 0 {
+		p.CanDeleteById(allowedFor)
+	}
+	if permissions&ListHistory != 0 {
+		p.CanListHistory(allowedFor)
+	}
+	if permissions&Revert != 0 {
+		p.CanRevertById(allowedFor)
+	}
+
+	return p
+}
@@ -0,0 +1,107 @@
+package sas
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/imthatgin/sas/pkg/migration"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func signTestJWT(t *testing.T, alg string, claims map[string]any, sign func(input []byte) []byte) string {
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	assert.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	assert.NoError(t, err)
+
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return input + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(input)))
+}
+
+func TestAuthenticate(t *testing.T) {
+	db := newTestOptionsDB(t)
+	assert.NoError(t, db.AutoMigrate(&testOptionsModel{}))
+
+	policy := NewPolicy[testOptionsModel](endpoints.AllEndpoints)
+	policy.
+		Allow(Read, IsAuthenticated()).
+		Allow(Create|Write|Delete, HasRole("editor"))
+
+	resource := FromModel[testOptionsModel]("entries", db, policy)
+	resource.CreateBindType(testBulkWrite{})
+
+	secret := []byte("secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	keys := NewAPIKeys(db)
+	static := StaticTokens(map[string]*Principal{"static": {ID: "static", Roles: []string{"editor"}}})
+
+	e := echo.New()
+	_, err = New(e, db, []Provider{&resource},
+		WithMigrations(migration.NewMigrator(), true),
+		WithAuthenticators(NewJWTAuthenticator(secret).Issuer("sas"), NewJWTAuthenticator(&rsaKey.PublicKey).RequireExpiry(), keys, static),
+	)
+	assert.NoError(t, err)
+	assert.True(t, db.Migrator().HasTable(APIKeyTableName))
+
+	serve := func(method string, header string, value string) int {
+		req := httptest.NewRequest(method, "/entries", strings.NewReader(`{"Text":"a"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(header, value)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodGet, "", ""))
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "Authorization", "Bearer static"))
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "Authorization", "Bearer unknown"))
+
+	hs256 := func(input []byte) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(input)
+		return mac.Sum(nil)
+	}
+	viewer := signTestJWT(t, "HS256", map[string]any{"sub": "viewer", "iss": "sas", "roles": []string{"viewer"}}, hs256)
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "Authorization", "Bearer "+viewer))
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "Authorization", "Bearer "+viewer))
+
+	expired := signTestJWT(t, "HS256", map[string]any{"sub": "viewer", "iss": "sas", "exp": time.Now().Add(-time.Hour).Unix()}, hs256)
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "Authorization", "Bearer "+expired))
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "Authorization", "Bearer "+viewer+"x"))
+
+	rs256 := func(input []byte) []byte {
+		digest := sha256.Sum256(input)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		assert.NoError(t, err)
+		return signature
+	}
+	editor := signTestJWT(t, "RS256", map[string]any{"sub": "editor", "roles": "editor", "exp": time.Now().Add(time.Hour).Unix()}, rs256)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "Authorization", "Bearer "+editor))
+
+	// The RS256 authenticator requires the exp claim.
+	unexpiring := signTestJWT(t, "RS256", map[string]any{"sub": "editor", "roles": "editor"}, rs256)
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodPost, "Authorization", "Bearer "+unexpiring))
+
+	key := &APIKey{Name: "ci", PrincipalID: "ci", Roles: "editor"}
+	apiKey, err := keys.Create(key)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "X-API-Key", apiKey))
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "X-API-Key", "sas_unknown"))
+
+	assert.NoError(t, keys.Revoke(key.ID))
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "X-API-Key", apiKey))
+}
@@ -2,26 +2,37 @@ package sas
 
 import (
 	"errors"
+	"fmt"
 	patch "github.com/geraldo-labs/merge-struct"
-	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
+	"strconv"
+)
+
+// Keys of the page sizes set on the Context by PageSize, for use by Paginate.
+const (
+	contextKeyDefaultPageSize = "sas.defaultPageSize"
+	contextKeyMaxPageSize     = "sas.maxPageSize"
 )
 
 type Queries[T any] struct {
-	listByIdQuery   func(c echo.Context, q *gorm.DB, id uint) (*T, error)
-	listAllQuery    func(c echo.Context, q *gorm.DB) ([]T, error)
-	writeByIdQuery  func(c echo.Context, q *gorm.DB, entity *T, new any) error
-	deleteByIdQuery func(c echo.Context, q *gorm.DB, entity T) error
+	listByIdQuery   func(c Context, q *gorm.DB, id uint) (*T, error)
+	listAllQuery    func(c Context, q *gorm.DB) ([]T, error)
+	writeByIdQuery  func(c Context, q *gorm.DB, entity *T, new any) error
+	deleteByIdQuery func(c Context, q *gorm.DB, entity T) error
 }
 
 // NewQueries returns a new instance of the query functions used by default.
 // The Queries struct has methods to override each query type.
 func NewQueries[T any]() Queries[T] {
 	return Queries[T]{
-		// TODO: Add pagination options
-		listAllQuery: func(c echo.Context, q *gorm.DB) ([]T, error) {
+		listAllQuery: func(c Context, q *gorm.DB) ([]T, error) {
+			q, err := Paginate(c, q)
+			if err != nil {
+				return nil, err
+			}
+
 			var result []T
-			tx := q.Find(&result)
+			tx := q.Scopes(TenantScope[T](c)).Find(&result)
 
 			if tx.Error != nil {
 				return nil, ErrorResourceNotFound
@@ -30,9 +41,9 @@ func NewQueries[T any]() Queries[T] {
 			return result, nil
 		},
 
-		listByIdQuery: func(c echo.Context, q *gorm.DB, id uint) (*T, error) {
+		listByIdQuery: func(c Context, q *gorm.DB, id uint) (*T, error) {
 			var result T
-			tx := q.First(&result, "id = ?", id)
+			tx := q.Scopes(TenantScope[T](c)).First(&result, "id = ?", id)
 
 			if tx.Error != nil {
 				if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
@@ -45,12 +56,22 @@ func NewQueries[T any]() Queries[T] {
 			return &result, nil
 		},
 
-		writeByIdQuery: func(c echo.Context, q *gorm.DB, entity *T, new any) error {
+		writeByIdQuery: func(c Context, q *gorm.DB, entity *T, new any) error {
+			owned, isOwned := any(entity).(tenantOwned)
+			var tenant string
+			if isOwned {
+				tenant = *owned.tenantID()
+			}
+
 			_, err := patch.Struct(entity, new)
 			if err != nil {
 				return err
 			}
 
+			if isOwned && *owned.tenantID() != tenant {
+				return errors.Join(ErrorResourceInvalidData, errors.New("the tenant of an entity can not be changed"))
+			}
+
 			tx := q.Save(entity)
 			if tx.Error != nil {
 				return tx.Error
@@ -59,8 +80,8 @@ func NewQueries[T any]() Queries[T] {
 			return nil
 		},
 
-		deleteByIdQuery: func(c echo.Context, q *gorm.DB, entity T) error {
-			tx := q.Delete(&entity)
+		deleteByIdQuery: func(c Context, q *gorm.DB, entity T) error {
+			tx := q.Scopes(TenantScope[T](c)).Delete(&entity)
 
 			if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
 				return ErrorResourceNotFound
@@ -75,26 +96,70 @@ func NewQueries[T any]() Queries[T] {
 	}
 }
 
-func (q *Queries[T]) ListByIdQuery(override func(c echo.Context, q *gorm.DB, id uint) (*T, error)) *Queries[T] {
+// Paginate applies the limit and offset query parameters of the request to q.
+// The page sizes configured with WithPageSize or PageSize apply when the request has no limit, or asks for too many entities.
+func Paginate(c Context, q *gorm.DB) (*gorm.DB, error) {
+	defaultSize, _ := c.Get(contextKeyDefaultPageSize).(int)
+	maxSize, _ := c.Get(contextKeyMaxPageSize).(int)
+
+	limit := defaultSize
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			return nil, errors.Join(ErrorResourceInvalidData, fmt.Errorf("invalid limit %q", limitStr))
+		}
+	}
+	if maxSize > 0 && (limit == 0 || limit > maxSize) {
+		limit = maxSize
+	}
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+
+	if offsetStr := c.QueryParam("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return nil, errors.Join(ErrorResourceInvalidData, fmt.Errorf("invalid offset %q", offsetStr))
+		}
+		q = q.Offset(offset)
+	}
+
+	return q, nil
+}
+
+func (q *Queries[T]) ListByIdQuery(override func(c Context, q *gorm.DB, id uint) (*T, error)) *Queries[T] {
 	q.listByIdQuery = override
 
 	return q
 }
 
-func (q *Queries[T]) ListAllQuery(override func(c echo.Context, q *gorm.DB) ([]T, error)) *Queries[T] {
+func (q *Queries[T]) ListAllQuery(override func(c Context, q *gorm.DB) ([]T, error)) *Queries[T] {
 	q.listAllQuery = override
 
 	return q
 }
 
-func (q *Queries[T]) WriteByIdQuery(override func(c echo.Context, q *gorm.DB, entity *T, new any) error) *Queries[T] {
+func (q *Queries[T]) WriteByIdQuery(override func(c Context, q *gorm.DB, entity *T, new any) error) *Queries[T] {
 	q.writeByIdQuery = override
 
 	return q
 }
 
-func (q *Queries[T]) DeleteByIdQuery(override func(c echo.Context, q *gorm.DB, entity T) error) *Queries[T] {
+func (q *Queries[T]) DeleteByIdQuery(override func(c Context, q *gorm.DB, entity T) error) *Queries[T] {
 	q.deleteByIdQuery = override
 
 	return q
 }
+
+// PageSize returns a middleware which sets the page sizes used by Paginate, as WithPageSize does for a Server.
+func PageSize(defaultSize int, maxSize int) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			c.Set(contextKeyDefaultPageSize, defaultSize)
+			c.Set(contextKeyMaxPageSize, maxSize)
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package sas
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"gopkg.in/yaml.v3"
+)
+
+// Condition decides whether a rule applies to a request. entity is nil for operations without one, such as
+// listing all entities and creating one.
+type Condition[T any] func(c Context, entity *T) bool
+
+// Is adapts a check on the principal of the request to a condition.
+func Is[T any](check Check) Condition[T] {
+	return func(c Context, entity *T) bool {
+		return check(PrincipalFrom(c))
+	}
+}
+
+// And holds when every one of conditions holds.
+func And[T any](conditions ...Condition[T]) Condition[T] {
+	return func(c Context, entity *T) bool {
+		for _, condition := range conditions {
+			if !condition(c, entity) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// Or holds when any of conditions holds.
+func Or[T any](conditions ...Condition[T]) Condition[T] {
+	return func(c Context, entity *T) bool {
+		for _, condition := range conditions {
+			if condition(c, entity) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// Not holds when condition does not.
+func Not[T any](condition Condition[T]) Condition[T] {
+	return func(c Context, entity *T) bool {
+		return !condition(c, entity)
+	}
+}
+
+// Effect is whether a rule allows or denies the operations it covers.
+type Effect string
+
+const (
+	EffectAllow Effect = "allow"
+	EffectDeny  Effect = "deny"
+)
+
+// Rule allows or denies permissions when its condition holds. A rule without a condition never applies.
+type Rule[T any] struct {
+	name        string
+	effect      Effect
+	permissions Permission
+	condition   Condition[T]
+}
+
+// Named sets the name of the rule.
+func (r *Rule[T]) Named(name string) *Rule[T] {
+	r.name = name
+	return r
+}
+
+// When applies the rule when condition holds.
+func (r *Rule[T]) When(condition Condition[T]) *Rule[T] {
+	r.condition = condition
+	return r
+}
+
+// Always applies the rule to every request.
+func (r *Rule[T]) Always() *Rule[T] {
+	return r.When(func(c Context, entity *T) bool {
+		return true
+	})
+}
+
+// ForRoles applies the rule to principals with any of roles.
+func (r *Rule[T]) ForRoles(roles ...string) *Rule[T] {
+	return r.When(Is[T](HasRole(roles...)))
+}
+
+// ForScopes applies the rule to principals with every one of scopes.
+func (r *Rule[T]) ForScopes(scopes ...string) *Rule[T] {
+	return r.When(Is[T](HasScope(scopes...)))
+}
+
+// ForAuthenticated applies the rule to every request with a principal.
+func (r *Rule[T]) ForAuthenticated() *Rule[T] {
+	return r.When(Is[T](IsAuthenticated()))
+}
+
+// String returns the name of the rule, or its effect and permissions when it has none.
+func (r *Rule[T]) String() string {
+	if r.name != "" {
+		return r.name
+	}
+
+	return string(r.effect) + " " + permissionName(r.permissions)
+}
+
+func (r *Rule[T]) applies(c Context, permission Permission, entity *T) bool {
+	return r.permissions&permission != 0 && r.condition != nil && r.condition(c, entity)
+}
+
+// Rules is a declarative set of rules, compiled to the predicates of a Policy.
+// An operation is allowed when an allow rule applies to it, and no deny rule does.
+type Rules[T any] struct {
+	rules      []*Rule[T]
+	conditions map[string]Condition[T]
+}
+
+// NewRules creates an empty set of rules, which denies every operation.
+func NewRules[T any]() *Rules[T] {
+	return &Rules[T]{
+		conditions: map[string]Condition[T]{},
+	}
+}
+
+// Allow adds a rule allowing permissions.
+func (r *Rules[T]) Allow(permissions Permission) *Rule[T] {
+	return r.add(EffectAllow, permissions)
+}
+
+// Deny adds a rule denying permissions, which takes precedence over the rules allowing them.
+func (r *Rules[T]) Deny(permissions Permission) *Rule[T] {
+	return r.add(EffectDeny, permissions)
+}
+
+func (r *Rules[T]) add(effect Effect, permissions Permission) *Rule[T] {
+	rule := &Rule[T]{effect: effect, permissions: permissions}
+	r.rules = append(r.rules, rule)
+	return rule
+}
+
+// Condition names condition, so rules loaded from a file can use it.
+func (r *Rules[T]) Condition(name string, condition Condition[T]) *Rules[T] {
+	r.conditions[name] = condition
+	return r
+}
+
+// decide evaluates the rules for an operation.
+func (r *Rules[T]) decide(c Context, permission Permission, entity *T) Decision {
+	var allowedBy *Rule[T]
+	for _, rule := range r.rules {
+		if !rule.applies(c, permission, entity) {
+			continue
+		}
+		if rule.effect == EffectDeny {
+			return Denied("denied by rule " + rule.String()).ByRule(rule.String())
+		}
+		if allowedBy == nil {
+			allowedBy = rule
+		}
+	}
+
+	if allowedBy == nil {
+		return Denied("no rule allows " + permissionName(permission))
+	}
+
+	return Allowed("allowed by rule " + allowedBy.String()).ByRule(allowedBy.String())
+}
+
+// Apply sets the predicates of the permissions covered by the rules on policy.
+func (r *Rules[T]) Apply(policy *Policy[T]) *Policy[T] {
+	var covered Permission
+	for _, rule := range r.rules {
+		covered |= rule.permissions
+	}
+
+	decide := func(permission Permission) func(c Context) Decision {
+		return func(c Context) Decision {
+			return r.decide(c, permission, nil)
+		}
+	}
+	decideFor := func(permission Permission) func(c Context, entity T) Decision {
+		return func(c Context, entity T) Decision {
+			return r.decide(c, permission, &entity)
+		}
+	}
+
+	if covered&ReadAll != 0 {
+		policy.DecideListAll(decide(ReadAll))
+	}
+	if covered&ReadById != 0 {
+		policy.DecideListById(decideFor(ReadById))
+	}
+	if covered&Write != 0 {
+		policy.DecideWriteById(decideFor(Write))
+	}
+	if covered&Create != 0 {
+		policy.DecideCreate(decide(Create))
+	}
+	if covered&Delete != 0 {
+		policy.DecideDeleteById(decideFor(Delete))
+	}
+	if covered&ListHistory != 0 {
+		policy.DecideListHistory(decideFor(ListHistory))
+	}
+	if covered&Revert != 0 {
+		policy.DecideRevertById(decideFor(Revert))
+	}
+
+	return policy
+}
+
+// Policy creates a policy for endpoints with the predicates of the rules.
+func (r *Rules[T]) Policy(endpoints endpoints.HttpEndpointType) Policy[T] {
+	policy := NewPolicy[T](endpoints)
+	r.Apply(&policy)
+	return policy
+}
+
+// permissionNames are the names of permissions in rule files.
+var permissionNames = map[string]Permission{
+	"readAll":     ReadAll,
+	"readById":    ReadById,
+	"read":        Read,
+	"write":       Write,
+	"create":      Create,
+	"delete":      Delete,
+	"listHistory": ListHistory,
+	"revert":      Revert,
+	"all":         All,
+}
+
+// permissionName names the single permissions in permissions, separated by commas.
+func permissionName(permissions Permission) string {
+	var names []string
+	for _, name := range []string{"readAll", "readById", "write", "create", "delete", "listHistory", "revert"} {
+		if permissions&permissionNames[name] != 0 {
+			names = append(names, name)
+		}
+	}
+
+	return strings.Join(names, ",")
+}
+
+// RuleFile is the format of rule files, in YAML or JSON.
+//
+//	rules:
+//	  - name: viewers
+//	    effect: allow
+//	    permissions: [read]
+//	    roles: [viewer]
+//	  - effect: allow
+//	    permissions: [write]
+//	    or: [{roles: [admin]}, {condition: owner}]
+type RuleFile struct {
+	Rules []RuleSpec `json:"rules" yaml:"rules"`
+}
+
+// RuleSpec is a rule of a RuleFile.
+type RuleSpec struct {
+	Name        string   `json:"name" yaml:"name"`
+	Effect      Effect   `json:"effect" yaml:"effect"`
+	Permissions []string `json:"permissions" yaml:"permissions"`
+
+	ConditionSpec `yaml:",inline"`
+}
+
+// ConditionSpec is the condition of a RuleSpec. Every field which is set has to hold.
+// Condition is the name of a condition added with Rules.Condition.
+type ConditionSpec struct {
+	Always        bool     `json:"always,omitempty" yaml:"always,omitempty"`
+	Authenticated bool     `json:"authenticated,omitempty" yaml:"authenticated,omitempty"`
+	Roles         []string `json:"roles,omitempty" yaml:"roles,omitempty"`
+	Scopes        []string `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+	Condition     string   `json:"condition,omitempty" yaml:"condition,omitempty"`
+
+	And []ConditionSpec `json:"and,omitempty" yaml:"and,omitempty"`
+	Or  []ConditionSpec `json:"or,omitempty" yaml:"or,omitempty"`
+	Not *ConditionSpec  `json:"not,omitempty" yaml:"not,omitempty"`
+}
+
+// Load adds the rules of a rule file in YAML or JSON.
+func (r *Rules[T]) Load(data []byte) error {
+	var file RuleFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("could not parse rules: %w", err)
+	}
+
+	return r.LoadSpecs(file.Rules...)
+}
+
+// LoadFile adds the rules of the rule file at path.
+func (r *Rules[T]) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return r.Load(data)
+}
+
+// LoadSpecs adds rules. No rule is added when any of them is invalid.
+func (r *Rules[T]) LoadSpecs(specs ...RuleSpec) error {
+	var rules []*Rule[T]
+	for i, spec := range specs {
+		name := spec.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i+1)
+		}
+
+		if spec.Effect != EffectAllow && spec.Effect != EffectDeny {
+			return fmt.Errorf("%s: unknown effect %q", name, spec.Effect)
+		}
+
+		var permissions Permission
+		for _, permissionName := range spec.Permissions {
+			permission, ok := permissionNames[permissionName]
+			if !ok {
+				return fmt.Errorf("%s: unknown permission %q", name, permissionName)
+			}
+			permissions |= permission
+		}
+		if permissions == 0 {
+			return fmt.Errorf("%s: no permissions", name)
+		}
+
+		condition, err := r.compile(spec.ConditionSpec)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		rules = append(rules, &Rule[T]{
+			name:        spec.Name,
+			effect:      spec.Effect,
+			permissions: permissions,
+			condition:   condition,
+		})
+	}
+
+	r.rules = append(r.rules, rules...)
+	return nil
+}
+
+// compile turns a condition of a rule file into a Condition.
+func (r *Rules[T]) compile(spec ConditionSpec) (Condition[T], error) {
+	var conditions []Condition[T]
+
+	if spec.Authenticated {
+		conditions = append(conditions, Is[T](IsAuthenticated()))
+	}
+	if len(spec.Roles) > 0 {
+		conditions = append(conditions, Is[T](HasRole(spec.Roles...)))
+	}
+	if len(spec.Scopes) > 0 {
+		conditions = append(conditions, Is[T](HasScope(spec.Scopes...)))
+	}
+	if spec.Condition != "" {
+		condition, ok := r.conditions[spec.Condition]
+		if !ok {
+			return nil, fmt.Errorf("unknown condition %q", spec.Condition)
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if len(spec.And) > 0 {
+		and, err := r.compileAll(spec.And)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, And(and...))
+	}
+	if len(spec.Or) > 0 {
+		or, err := r.compileAll(spec.Or)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, Or(or...))
+	}
+	if spec.Not != nil {
+		not, err := r.compile(*spec.Not)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, Not(not))
+	}
+
+	if len(conditions) == 0 {
+		if !spec.Always {
+			return nil, fmt.Errorf("no condition, use always to apply to every request")
+		}
+		return func(c Context, entity *T) bool { return true }, nil
+	}
+	if spec.Always {
+		return nil, fmt.Errorf("always can not be combined with other conditions")
+	}
+
+	return And(conditions...), nil
+}
+
+func (r *Rules[T]) compileAll(specs []ConditionSpec) ([]Condition[T], error) {
+	conditions := make([]Condition[T], 0, len(specs))
+	for _, spec := range specs {
+		condition, err := r.compile(spec)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	return conditions, nil
+}
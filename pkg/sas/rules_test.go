@@ -0,0 +1,97 @@
+package sas
+
+import (
+	"testing"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type testRulesModel struct {
+	Owner string
+}
+
+func isTestOwner(c Context, entity *testRulesModel) bool {
+	principal := PrincipalFrom(c)
+	return entity != nil && principal != nil && entity.Owner == principal.ID
+}
+
+func newTestRulesContext(principal *Principal) Context {
+	c := echo.New().NewContext(nil, nil)
+	if principal != nil {
+		c.Set(contextKeyPrincipal, principal)
+	}
+
+	return c
+}
+
+func TestRules(t *testing.T) {
+	rules := NewRules[testRulesModel]()
+	rules.Allow(Read).ForRoles("viewer")
+	rules.Allow(Write).When(Or(isTestOwner, Is[testRulesModel](HasRole("admin"))))
+	rules.Allow(Create).When(And(Is[testRulesModel](HasScope("entries:write")), Not(Is[testRulesModel](HasRole("banned")))))
+	rules.Deny(Delete).Always()
+
+	policy := rules.Policy(endpoints.AllEndpoints)
+
+	anonymous := newTestRulesContext(nil)
+	viewer := newTestRulesContext(&Principal{ID: "a", Roles: []string{"viewer"}, Scopes: []string{"entries:write"}})
+	banned := newTestRulesContext(&Principal{ID: "b", Roles: []string{"banned"}, Scopes: []string{"entries:write"}})
+	admin := newTestRulesContext(&Principal{ID: "c", Roles: []string{"admin"}})
+
+	assert.False(t, policy.canListAll(anonymous))
+	assert.True(t, policy.canListAll(viewer))
+	assert.True(t, policy.canListById(viewer, testRulesModel{}))
+
+	assert.True(t, policy.canWriteById(viewer, testRulesModel{Owner: "a"}))
+	assert.False(t, policy.canWriteById(viewer, testRulesModel{Owner: "b"}))
+	assert.True(t, policy.canWriteById(admin, testRulesModel{Owner: "b"}))
+
+	assert.True(t, policy.canCreate(viewer))
+	assert.False(t, policy.canCreate(banned))
+
+	assert.False(t, policy.canDeleteById(admin, testRulesModel{}))
+
+	// Deny rules take precedence over the rules allowing an operation.
+	rules.Deny(Read).ForRoles("banned")
+	rules.Allow(Read).ForRoles("banned")
+	assert.False(t, policy.canListAll(banned))
+}
+
+func TestRules_Load(t *testing.T) {
+	rules := NewRules[testRulesModel]().Condition("owner", isTestOwner)
+	err := rules.Load([]byte(`
+rules:
+  - name: viewers
+    effect: allow
+    permissions: [read]
+    roles: [viewer]
+  - name: owners
+    effect: allow
+    permissions: [write, delete]
+    or:
+      - roles: [admin]
+      - condition: owner
+  - effect: deny
+    permissions: [delete]
+    not: {authenticated: true}
+`))
+	assert.NoError(t, err)
+
+	policy := rules.Policy(endpoints.AllEndpoints)
+	viewer := newTestRulesContext(&Principal{ID: "a", Roles: []string{"viewer"}})
+
+	assert.True(t, policy.canListAll(viewer))
+	assert.True(t, policy.canDeleteById(viewer, testRulesModel{Owner: "a"}))
+	assert.False(t, policy.canWriteById(viewer, testRulesModel{Owner: "b"}))
+	assert.False(t, policy.canCreate(viewer))
+	assert.False(t, policy.canDeleteById(newTestRulesContext(nil), testRulesModel{}))
+
+	// JSON is loaded the same way.
+	assert.NoError(t, NewRules[testRulesModel]().Load([]byte(`{"rules":[{"effect":"deny","permissions":["all"],"always":true}]}`)))
+
+	assert.ErrorContains(t, rules.Load([]byte(`{"rules":[{"effect":"allow","permissions":["read"]}]}`)), "no condition")
+	assert.ErrorContains(t, rules.Load([]byte(`{"rules":[{"effect":"allow","permissions":["fly"],"always":true}]}`)), "unknown permission")
+	assert.ErrorContains(t, rules.Load([]byte(`{"rules":[{"effect":"allow","permissions":["read"],"condition":"missing"}]}`)), "unknown condition")
+}
@@ -1,42 +1,158 @@
 package sas
 
 import (
+	"errors"
+	"net/http"
+	"reflect"
+
 	"github.com/imthatgin/sas/pkg/migration"
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/gommon/log"
 	"gorm.io/gorm"
 )
 
+var ErrorMigrationsFailed = errors.New("migrations could not be applied")
+
 type Provider interface {
-	Register(e *echo.Echo)
+	Register(r Router)
+}
+
+// ModelProvider is implemented by resources which are backed by a database model, such as ModelResource.
+type ModelProvider interface {
+	Model() any
+}
+
+// Models returns the database models of the resources, including those of versions.
+// A model used by several versions is only returned once.
+func Models(resources []Provider) []any {
+	var models []any
+	seen := map[reflect.Type]bool{}
+	for _, resource := range resources {
+		var found []any
+		if version, ok := resource.(*Version); ok {
+			found = Models(version.Resources)
+		} else if modelProvider, ok := resource.(ModelProvider); ok {
+			found = []any{modelProvider.Model()}
+		}
+
+		for _, model := range found {
+			if !seen[reflect.TypeOf(model)] {
+				seen[reflect.TypeOf(model)] = true
+				models = append(models, model)
+			}
+		}
+	}
+
+	return models
 }
 
 type Server struct {
-	e  *echo.Echo
-	db *gorm.DB
+	e      *echo.Echo
+	db     *gorm.DB
+	config config
 
 	// Reference database models
 	resources []Provider
 }
 
-func New(echo *echo.Echo, db *gorm.DB, resources []Provider) *Server {
-	echo.HTTPErrorHandler = ManagedModelErrorHandler
-
+// New applies the migrations to db, and then registers the resources on echo.
+// By default the migrations of migration.Default are run, and New fails without registering anything
+// when they cannot be applied. See Option for the available configuration.
+func New(e *echo.Echo, db *gorm.DB, resources []Provider, options ...Option) (*Server, error) {
 	s := &Server{
-		e:  echo,
-		db: db,
+		e:      e,
+		db:     db,
+		config: newConfig(options),
 
 		resources: resources,
 	}
 
+	if s.config.webhooks != nil && s.config.webhooks.logger == nil {
+		s.config.webhooks.logger = s.config.logger
+	}
+	if s.config.outbox != nil && s.config.outbox.logger == nil {
+		s.config.outbox.logger = s.config.logger
+	}
+	if tenancy := s.config.tenancy; tenancy != nil && tenancy.databases != nil && tenancy.migrator == nil {
+		tenancy.migrator = s.config.migrator
+	}
+
+	var targets []serverOptionTarget
+	var optionsErr error
+	walkResources(s.resources, func(resource Provider) {
+		if target, ok := resource.(serverOptionTarget); ok {
+			targets = append(targets, target)
+			optionsErr = errors.Join(optionsErr, target.applyServerOptions(&s.config))
+		}
+	})
+	if optionsErr != nil {
+		return nil, optionsErr
+	}
+
+	if s.config.migrator != nil {
+		if s.config.auditor != nil {
+			s.config.auditor.RegisterMigration(s.config.migrator)
+		}
+		if s.config.webhooks != nil {
+			s.config.webhooks.RegisterMigration(s.config.migrator)
+		}
+		if s.config.outbox != nil {
+			s.config.outbox.RegisterMigration(s.config.migrator)
+		}
+		for _, authenticator := range s.config.authenticators {
+			if migrated, ok := authenticator.(interface{ RegisterMigration(m *migration.Migrator) }); ok {
+				migrated.RegisterMigration(s.config.migrator)
+			}
+		}
+		for _, target := range targets {
+			target.RegisterHistoryMigration(s.config.migrator)
+		}
+
+		err := s.config.migrator.Run(s.db)
+		if err != nil {
+			if s.config.migrationsFatal {
+				return nil, errors.Join(ErrorMigrationsFailed, err)
+			}
+			s.config.logger.Errorf("Could not run migrations: %s", err)
+		}
+	}
+
+	if s.config.errorHandler != nil {
+		e.HTTPErrorHandler = s.config.errorHandler
+	}
+	if s.config.jsonSerializer != nil {
+		e.JSONSerializer = s.config.jsonSerializer
+	}
+	if s.config.versionHeader != "" {
+		e.Pre(s.versionSelector)
+	}
+
+	r := s.router()
 	for _, resource := range s.resources {
-		resource.Register(echo)
+		resource.Register(r)
 	}
 
-	err := migration.RunMigrations(s.db)
-	if err != nil {
-		log.Error("Could not run migrations: ", err)
+	return s, nil
+}
+
+// BasePath returns the prefix all resources are registered under, which is empty by default.
+func (s *Server) BasePath() string {
+	return s.config.basePath
+}
+
+// serveGet adds a GET route at path, under the base path and behind the middlewares of the resources, so handlers
+// see the principal of the request.
+func (s *Server) serveGet(path string, handler HandlerFunc) {
+	s.router().Add(http.MethodGet, path, handler)
+}
+
+func (s *Server) router() Router {
+	r := NewEchoRouter(s.e.Group(s.config.basePath), s.config.middlewares...)
+	if s.config.defaultPageSize > 0 || s.config.maxPageSize > 0 {
+		r = r.Group("", PageSize(s.config.defaultPageSize, s.config.maxPageSize))
+	}
+	if len(s.config.authenticators) > 0 {
+		r = r.Group("", Authenticate(false, s.config.authenticators...))
 	}
 
-	return s
+	return r
 }
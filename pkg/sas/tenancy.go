@@ -0,0 +1,285 @@
+package sas
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/imthatgin/sas/pkg/migration"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Keys of the tenant and its database set on the Context by Tenancy.
+const (
+	contextKeyTenant   = "sas.tenant"
+	contextKeyTenantDB = "sas.tenantDB"
+)
+
+// ErrorTenantUnresolved is returned when a request has no tenant, or a tenant model is used without one.
+var ErrorTenantUnresolved = errors.New("tenant could not be resolved")
+
+// ErrorTenantDatabasesUnsupported is returned by New for resources using the databases of tenants together with
+// an auditor or an outbox, whose records would be written to the databases of tenants, where they are never read.
+var ErrorTenantDatabasesUnsupported = errors.New("audit and outbox are not supported with the databases of tenants")
+
+// TenantModel is a DefaultModel owned by a tenant. The default queries of resources of models embedding it
+// only see the entities of the tenant of the request, create sets TenantID, and writes can not change it.
+type TenantModel struct {
+	DefaultModel
+	TenantID string `gorm:"size:255;index"`
+}
+
+func (m *TenantModel) tenantID() *string {
+	return &m.TenantID
+}
+
+func (m *TenantModel) tenantRequired() bool {
+	return true
+}
+
+// tenantOwned is implemented by models owned by a tenant, such as those embedding TenantModel. Their table has
+// a tenant_id column. Models which do not require a tenant belong to no tenant when the request has none.
+type tenantOwned interface {
+	tenantID() *string
+	tenantRequired() bool
+}
+
+// isTenantModel reports whether T is owned by a tenant, such as by embedding TenantModel.
+func isTenantModel[T any]() bool {
+	_, ok := any(new(T)).(tenantOwned)
+	return ok
+}
+
+// Tenant returns the tenant of the request, as resolved by Tenancy, or an empty string.
+func Tenant(c Context) string {
+	tenant, _ := c.Get(contextKeyTenant).(string)
+	return tenant
+}
+
+// TenantScope limits a query to the entities of the tenant of the request, when T embeds TenantModel.
+// It adds ErrorTenantUnresolved to the query when the request has no tenant. Custom queries can use it with Scopes.
+func TenantScope[T any](c Context) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		owned, ok := any(new(T)).(tenantOwned)
+		if !ok {
+			return db
+		}
+
+		tenant := Tenant(c)
+		if tenant == "" && owned.tenantRequired() {
+			_ = db.AddError(ErrorTenantUnresolved)
+			return db
+		}
+
+		return db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "tenant_id"}, Value: tenant})
+	}
+}
+
+// assignTenant sets the tenant of the request on an entity, when T embeds TenantModel.
+func assignTenant[T any](c Context, entity *T) error {
+	owned, ok := any(entity).(tenantOwned)
+	if !ok {
+		return nil
+	}
+
+	tenant := Tenant(c)
+	if tenant == "" && owned.tenantRequired() {
+		return ErrorTenantUnresolved
+	}
+	*owned.tenantID() = tenant
+
+	return nil
+}
+
+// TenantResolver reads the tenant of a request. An empty tenant is rejected like an error.
+type TenantResolver func(c Context) (string, error)
+
+// TenantFromHeader reads the tenant from a request header, such as X-Tenant-ID.
+func TenantFromHeader(header string) TenantResolver {
+	return func(c Context) (string, error) {
+		return c.Request().Header.Get(header), nil
+	}
+}
+
+// TenantFromSubdomain reads the tenant from the first label of the host, such as acme of acme.example.com.
+// Hosts with fewer than three labels have no tenant.
+func TenantFromSubdomain() TenantResolver {
+	return func(c Context) (string, error) {
+		host := c.Request().Host
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+
+		labels := strings.Split(host, ".")
+		if len(labels) < 3 {
+			return "", nil
+		}
+
+		return labels[0], nil
+	}
+}
+
+// TenantFromPrincipal reads the tenant of the principal of the request, as set by Authenticate.
+func TenantFromPrincipal() TenantResolver {
+	return func(c Context) (string, error) {
+		if principal := PrincipalFrom(c); principal != nil {
+			return principal.Tenant, nil
+		}
+
+		return "", nil
+	}
+}
+
+// TenantFromClaim reads the tenant from a claim of the token stored under key, such as user for the JWT
+// middleware of echo. The value can be a map of claims, or a token with a Claims field holding one.
+// The token has to be verified by a middleware before the tenant is resolved.
+func TenantFromClaim(key string, claim string) TenantResolver {
+	return func(c Context) (string, error) {
+		claims := reflect.Indirect(reflect.ValueOf(c.Get(key)))
+		if claims.Kind() == reflect.Struct {
+			claims = claims.FieldByName("Claims")
+		}
+		for claims.Kind() == reflect.Interface || claims.Kind() == reflect.Pointer {
+			claims = claims.Elem()
+		}
+		if claims.Kind() != reflect.Map || claims.Type().Key().Kind() != reflect.String {
+			return "", nil
+		}
+
+		value := claims.MapIndex(reflect.ValueOf(claim).Convert(claims.Type().Key()))
+		if !value.IsValid() {
+			return "", nil
+		}
+
+		return fmt.Sprint(value.Interface()), nil
+	}
+}
+
+// Tenancy resolves the tenant of each request, and optionally a separate database for each tenant.
+type Tenancy struct {
+	resolver  TenantResolver
+	databases func(tenant string) (*gorm.DB, error)
+	migrator  *migration.Migrator
+
+	mu       sync.Mutex
+	migrated map[string]bool
+}
+
+// NewTenancy creates a tenancy resolving the tenant with resolver, which shares the database of the resources
+// between tenants.
+func NewTenancy(resolver TenantResolver) *Tenancy {
+	return &Tenancy{
+		resolver: resolver,
+		migrated: map[string]bool{},
+	}
+}
+
+// Databases sets the database of each tenant, which the resources use instead of their own.
+// databases is called for every request, and should return a cached connection. Resources using them can not
+// have an auditor or an outbox, see ErrorTenantDatabasesUnsupported.
+func (t *Tenancy) Databases(databases func(tenant string) (*gorm.DB, error)) *Tenancy {
+	t.databases = databases
+	return t
+}
+
+// Migrations sets the migrations run on the database of each tenant, the first time it is used.
+// WithTenancy uses the migrations of the server, unless they are set.
+func (t *Tenancy) Migrations(m *migration.Migrator) *Tenancy {
+	t.migrator = m
+	return t
+}
+
+// Middleware resolves the tenant of the request, and its database. Requests without a tenant fail with
+// ErrorTenantUnresolved.
+func (t *Tenancy) Middleware() MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			tenant, err := t.resolver(c)
+			if err != nil {
+				return errors.Join(ErrorTenantUnresolved, err)
+			}
+			if tenant == "" {
+				return ErrorTenantUnresolved
+			}
+			c.Set(contextKeyTenant, tenant)
+
+			if t.databases != nil {
+				db, err := t.database(tenant)
+				if err != nil {
+					return errors.Join(ErrorDatabaseIssue, err)
+				}
+				c.Set(contextKeyTenantDB, db)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// database returns the database of tenant, and migrates it the first time.
+func (t *Tenancy) database(tenant string) (*gorm.DB, error) {
+	db, err := t.databases(tenant)
+	if err != nil || t.migrator == nil {
+		return db, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.migrated[tenant] {
+		if err := t.migrator.Run(db); err != nil {
+			return nil, err
+		}
+		t.migrated[tenant] = true
+	}
+
+	return db, nil
+}
+
+// database returns the database of the tenant of the request, or the database of the resource.
+func (mr *ModelResource[T]) database(c Context) *gorm.DB {
+	if db, ok := c.Get(contextKeyTenantDB).(*gorm.DB); ok && db != nil {
+		return db
+	}
+
+	return mr.db
+}
+
+// Tenancy resolves the tenant of every request to the resource with tenancy, and uses the database of the tenant
+// when tenancy has databases. Unlike WithTenancy, it also applies to models which do not embed TenantModel.
+func (mr *ModelResource[T]) Tenancy(tenancy *Tenancy) {
+	mr.tenancy = tenancy
+}
+
+// checkTenantDatabases returns ErrorTenantDatabasesUnsupported when the resource uses the databases of tenants
+// together with an auditor or an outbox.
+func (mr *ModelResource[T]) checkTenantDatabases() error {
+	if mr.tenancy == nil || mr.tenancy.databases == nil {
+		return nil
+	}
+
+	var unsupported []string
+	if mr.auditor != nil {
+		unsupported = append(unsupported, "an auditor")
+	}
+	if mr.outbox != nil {
+		unsupported = append(unsupported, "an outbox")
+	}
+	if len(unsupported) == 0 {
+		return nil
+	}
+
+	return errors.Join(ErrorTenantDatabasesUnsupported,
+		fmt.Errorf("resource %s uses the databases of tenants with %s", mr.Name, strings.Join(unsupported, " and ")))
+}
+
+// tenancyByDefault enables tenancy for the resource when T embeds TenantModel, unless it has its own.
+func (mr *ModelResource[T]) tenancyByDefault(tenancy *Tenancy) {
+	if mr.tenancy == nil && isTenantModel[T]() {
+		mr.tenancy = tenancy
+	}
+}
@@ -0,0 +1,302 @@
+package sas
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/imthatgin/sas/pkg/migration"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type testTenantModel struct {
+	TenantModel
+
+	Text string
+}
+
+type testTenantWrite struct {
+	Text     string
+	TenantID string
+}
+
+func newTestTenantResource(db *gorm.DB) ModelResource[testTenantModel] {
+	policy := NewPolicy[testTenantModel](endpoints.AllEndpoints)
+	policy.
+		CanListAll(func(c Context) bool { return true }).
+		CanCreate(func(c Context) bool { return true }).
+		CanListById(func(c Context, entity testTenantModel) bool { return true }).
+		CanWriteById(func(c Context, entity testTenantModel) bool { return true }).
+		CanDeleteById(func(c Context, entity testTenantModel) bool { return true })
+
+	resource := FromModel[testTenantModel]("entries", db, policy)
+	resource.CreateBindType(testTenantWrite{})
+	resource.WriteBindType(testTenantWrite{})
+
+	return resource
+}
+
+func TestTenancy(t *testing.T) {
+	db := newTestOptionsDB(t)
+	assert.NoError(t, db.AutoMigrate(&testTenantModel{}))
+
+	resource := newTestTenantResource(db)
+
+	e := echo.New()
+	_, err := New(e, db, []Provider{&resource}, WithoutMigrations(), WithTenancy(NewTenancy(TenantFromHeader("X-Tenant"))))
+	assert.NoError(t, err)
+
+	serve := func(method string, target string, body string, tenant string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Tenant", tenant)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/entries", `{"Text":"a","TenantID":"b"}`, "a").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/entries", `{"Text":"b"}`, "b").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/entries", "", "").Code)
+
+	rec := serve(http.MethodGet, "/entries", "", "a")
+	var entries []testTenantModel
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &entries))
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "a", entries[0].TenantID)
+
+	// The entities of other tenants do not exist for the tenant of the request.
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/entries/2", "", "a").Code)
+	assert.Equal(t, http.StatusNotFound, serve(http.MethodPut, "/entries/2", `{"Text":"changed"}`, "a").Code)
+	assert.NotEqual(t, http.StatusOK, serve(http.MethodDelete, "/entries/2", "", "a").Code)
+
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPut, "/entries/1", `{"TenantID":"b"}`, "a").Code)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPut, "/entries/1", `{"Text":"changed"}`, "a").Code)
+
+	var stored []testTenantModel
+	assert.NoError(t, db.Order("id").Find(&stored).Error)
+	assert.Len(t, stored, 2)
+	assert.Equal(t, "a", stored[0].TenantID)
+	assert.Equal(t, "changed", stored[0].Text)
+	assert.Equal(t, "b", stored[1].Text)
+}
+
+func TestTenancy_OnlyTenantResources(t *testing.T) {
+	db := newTestOptionsDB(t)
+	assert.NoError(t, db.AutoMigrate(&testTenantModel{}, &testOptionsModel{}))
+
+	tenanted := newTestTenantResource(db)
+
+	policy := NewPolicy[testOptionsModel](endpoints.AllEndpoints)
+	policy.CanListAll(func(c Context) bool { return true })
+	shared := FromModel[testOptionsModel]("shared", db, policy)
+
+	e := echo.New()
+	_, err := New(e, db, []Provider{&tenanted, &shared}, WithoutMigrations(), WithTenancy(NewTenancy(TenantFromHeader("X-Tenant"))))
+	assert.NoError(t, err)
+
+	serve := func(target string, tenant string) int {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("X-Tenant", tenant)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Resources of models without a tenant do not need one.
+	assert.Equal(t, http.StatusOK, serve("/shared", ""))
+	assert.Equal(t, http.StatusBadRequest, serve("/entries", ""))
+	assert.Equal(t, http.StatusOK, serve("/entries", "a"))
+}
+
+func TestTenancy_ResourceAuthentication(t *testing.T) {
+	db := newTestOptionsDB(t)
+	assert.NoError(t, db.AutoMigrate(&testTenantModel{}))
+
+	// The tenant is read from the principal authenticated by the middlewares of the resource.
+	resource := newTestTenantResource(db)
+	resource.Middlewares(Authenticate(true, StaticTokens(map[string]*Principal{"a": {ID: "user", Tenant: "a"}})))
+
+	e := echo.New()
+	_, err := New(e, db, []Provider{&resource}, WithoutMigrations(), WithTenancy(NewTenancy(TenantFromPrincipal())))
+	assert.NoError(t, err)
+
+	serve := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/entries", strings.NewReader(`{"Text":"a"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("a"))
+	assert.Equal(t, http.StatusUnauthorized, serve("b"))
+
+	var created testTenantModel
+	assert.NoError(t, db.First(&created).Error)
+	assert.Equal(t, "a", created.TenantID)
+}
+
+func TestTenancyDatabases(t *testing.T) {
+	databases := map[string]*gorm.DB{
+		"a": newTestOptionsDB(t),
+		"b": newTestOptionsDB(t),
+	}
+
+	m := migration.NewMigrator()
+	m.RegisterSystemMigration("001_entries", func(db *gorm.DB) error {
+		return db.AutoMigrate(&testTenantModel{})
+	}, func(db *gorm.DB) error {
+		return db.Migrator().DropTable(&testTenantModel{})
+	})
+
+	tenancy := NewTenancy(TenantFromHeader("X-Tenant")).Databases(func(tenant string) (*gorm.DB, error) {
+		return databases[tenant], nil
+	})
+
+	shared := newTestOptionsDB(t)
+	resource := newTestTenantResource(shared)
+
+	e := echo.New()
+	_, err := New(e, shared, []Provider{&resource}, WithMigrations(m, true), WithTenancy(tenancy))
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/entries", strings.NewReader(`{"Text":"a"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Tenant", "a")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var count int64
+	assert.NoError(t, databases["a"].Model(&testTenantModel{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+	assert.NoError(t, shared.Model(&testTenantModel{}).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+
+	// Tenant databases are migrated the first time they are used.
+	assert.False(t, databases["b"].Migrator().HasTable(&testTenantModel{}))
+
+	// Audit and outbox records would be written to the databases of tenants, where they are never read.
+	for _, option := range []Option{WithAuditor(NewAuditor()), WithOutbox(NewOutbox(shared, nil, OutboxOptions{}))} {
+		resource := newTestTenantResource(shared)
+		_, err = New(echo.New(), shared, []Provider{&resource}, WithoutMigrations(), WithTenancy(tenancy), option)
+		assert.ErrorIs(t, err, ErrorTenantDatabasesUnsupported)
+	}
+}
+
+func TestTenantResolvers(t *testing.T) {
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "acme.example.com:8080"
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	tenant, err := TenantFromSubdomain()(c)
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", tenant)
+
+	req.Host = "example.com"
+	tenant, _ = TenantFromSubdomain()(c)
+	assert.Empty(t, tenant)
+
+	type token struct {
+		Claims any
+	}
+	type mapClaims map[string]any
+	c.Set("user", &token{Claims: mapClaims{"tenant": "acme"}})
+
+	tenant, err = TenantFromClaim("user", "tenant")(c)
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", tenant)
+
+	tenant, _ = TenantFromClaim("user", "missing")(c)
+	assert.Empty(t, tenant)
+}
+
+func TestTenancy_EventsAndWebhooks(t *testing.T) {
+	db := newTestOptionsDB(t)
+	assert.NoError(t, db.AutoMigrate(&testTenantModel{}))
+
+	resource := newTestTenantResource(db)
+	resource.Events(NewBroker(16))
+
+	policy := NewPolicy[Webhook](endpoints.AllEndpoints)
+	policy.CanListAll(func(c Context) bool { return true })
+	webhooks := NewWebhooks(db, DefaultWebhookOptions)
+	hooks := webhooks.Resource("webhooks", policy)
+
+	e := echo.New()
+	_, err := New(e, db, []Provider{&resource, &hooks},
+		WithMigrations(migration.NewMigrator(), true),
+		WithWebhooks(webhooks),
+		WithTenancy(NewTenancy(TenantFromHeader("X-Tenant"))),
+	)
+	assert.NoError(t, err)
+
+	assert.NoError(t, db.Create(&[]Webhook{
+		{URL: "http://a.example", Resource: "entries", TenantID: "a"},
+		{URL: "http://b.example", Resource: "entries", TenantID: "b"},
+		{URL: "http://none.example", Resource: "entries"},
+	}).Error)
+
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/entries/_events", nil)
+	assert.NoError(t, err)
+	req.Header.Set("X-Tenant", "b")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+
+	for _, tenant := range []string{"a", "b"} {
+		req := httptest.NewRequest(http.MethodPost, "/entries", strings.NewReader(`{"Text":"`+tenant+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Tenant", tenant)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	// Subscribers only receive the events of their tenant.
+	stream := bufio.NewReader(resp.Body)
+	var event string
+	for !strings.HasPrefix(event, "data: ") {
+		event, err = stream.ReadString('\n')
+		assert.NoError(t, err)
+	}
+	assert.Contains(t, event, `"Text":"b"`)
+
+	// Webhooks only receive the events of their tenant.
+	var deliveries []WebhookDelivery
+	assert.NoError(t, db.Order("id").Find(&deliveries).Error)
+	assert.Len(t, deliveries, 2)
+	assert.Equal(t, uint(1), deliveries[0].WebhookID)
+	assert.Equal(t, "a", deliveries[0].Tenant)
+	assert.Equal(t, uint(2), deliveries[1].WebhookID)
+	assert.Equal(t, "b", deliveries[1].Tenant)
+
+	// Tenants only see their own webhooks.
+	req = httptest.NewRequest(http.MethodGet, "/webhooks", nil)
+	req.Header.Set("X-Tenant", "a")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var listed []Webhook
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	assert.Len(t, listed, 1)
+	assert.Equal(t, "http://a.example", listed[0].URL)
+}
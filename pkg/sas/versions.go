@@ -0,0 +1,171 @@
+package sas
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+var ErrorUnknownVersion = errors.New("requested API version does not exist")
+
+// Version groups resources under a version prefix, such as /v1/entries.
+// The same model can be part of several versions, each with its own bind types, read type and policy.
+type Version struct {
+	// Name is used as the path prefix, such as "v1".
+	Name      string
+	Resources []Provider
+
+	// Deprecation is when the version was, or will be, deprecated. It is sent as the Deprecation header.
+	Deprecation time.Time
+	// Sunset is when the version will be removed. It is sent as the Sunset header.
+	Sunset time.Time
+	// Link points to documentation on how to move off the version, and is sent as a Link header.
+	Link string
+}
+
+// NewVersion creates a version from its resources, which are registered under /name.
+func NewVersion(name string, resources ...Provider) *Version {
+	return &Version{
+		Name:      strings.Trim(name, "/"),
+		Resources: resources,
+	}
+}
+
+// Deprecate marks the version as deprecated since the given time, to be removed at sunset.
+// Either time may be zero, and link may be empty.
+func (v *Version) Deprecate(since time.Time, sunset time.Time, link string) *Version {
+	v.Deprecation = since
+	v.Sunset = sunset
+	v.Link = link
+
+	return v
+}
+
+// Deprecated reports whether the version has been marked as deprecated.
+func (v *Version) Deprecated() bool {
+	return !v.Deprecation.IsZero()
+}
+
+// Register is called automatically by SAS, and registers the resources of the version under its name.
+func (v *Version) Register(r Router) {
+	var middlewares []MiddlewareFunc
+	if v.Deprecated() || !v.Sunset.IsZero() {
+		middlewares = append(middlewares, v.deprecationHeaders)
+	}
+
+	group := r.Group("/"+v.Name, middlewares...)
+	for _, resource := range v.Resources {
+		resource.Register(group)
+	}
+}
+
+// deprecationHeaders sets the headers of RFC 9745 and RFC 8594 on every response of the version.
+func (v *Version) deprecationHeaders(next HandlerFunc) HandlerFunc {
+	return func(c Context) error {
+		header := ResponseHeader(c)
+
+		if v.Deprecated() {
+			header.Set("Deprecation", "@"+strconv.FormatInt(v.Deprecation.Unix(), 10))
+		}
+		if !v.Sunset.IsZero() {
+			header.Set("Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if v.Link != "" {
+			header.Add("Link", "<"+v.Link+`>; rel="deprecation"`)
+		}
+
+		return next(c)
+	}
+}
+
+// walkResources calls fn for every resource, including those of versions.
+func walkResources(resources []Provider, fn func(resource Provider)) {
+	for _, resource := range resources {
+		if version, ok := resource.(*Version); ok {
+			walkResources(version.Resources, fn)
+			continue
+		}
+
+		fn(resource)
+	}
+}
+
+// describeResources describes resources registered under prefix, descending into versions.
+func describeResources(resources []Provider, prefix string) []ResourceDescription {
+	var descriptions []ResourceDescription
+	for _, resource := range resources {
+		if version, ok := resource.(*Version); ok {
+			for _, description := range describeResources(version.Resources, prefix+"/"+version.Name) {
+				description.Version = version.Name
+				description.Deprecated = version.Deprecated()
+				descriptions = append(descriptions, description)
+			}
+			continue
+		}
+
+		describer, ok := resource.(Describer)
+		if !ok {
+			continue
+		}
+
+		description := describer.Describe()
+		for i := range description.Routes {
+			description.Routes[i].Path = prefix + description.Routes[i].Path
+		}
+		descriptions = append(descriptions, description)
+	}
+
+	return descriptions
+}
+
+// versionSelector routes requests for versioned resources without a version in the path, such as /entries,
+// to the version named by the configured header, or the default version when the header is absent.
+func (s *Server) versionSelector(next echo.HandlerFunc) echo.HandlerFunc {
+	versions := map[string]bool{}
+	names := map[string]bool{}
+	for _, resource := range s.resources {
+		if version, ok := resource.(*Version); ok {
+			versions[version.Name] = true
+			for _, description := range describeResources(version.Resources, "") {
+				names[strings.Trim(description.Name, "/")] = true
+			}
+		}
+	}
+
+	return func(c echo.Context) error {
+		request := c.Request()
+
+		rest, ok := strings.CutPrefix(request.URL.Path, s.config.basePath)
+		if !ok || !strings.HasPrefix(rest, "/") {
+			return next(c)
+		}
+
+		// A version in the path always wins over the header.
+		first, _, _ := strings.Cut(strings.TrimPrefix(rest, "/"), "/")
+		if versions[first] || !names[first] {
+			return next(c)
+		}
+
+		c.Response().Header().Add("Vary", s.config.versionHeader)
+
+		version := request.Header.Get(s.config.versionHeader)
+		if version == "" {
+			version = s.config.defaultVersion
+		}
+		if version == "" {
+			return next(c)
+		}
+		if !versions[version] {
+			return ErrorUnknownVersion
+		}
+
+		request.URL.Path = s.config.basePath + "/" + version + rest
+		request.URL.RawPath = ""
+
+		return next(c)
+	}
+}
@@ -0,0 +1,96 @@
+package sas
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/imthatgin/sas/pkg/openapi"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type testVersionRead struct {
+	ID   uint   `json:"id"`
+	Body string `json:"body"`
+}
+
+func TestVersion(t *testing.T) {
+	db := newTestOptionsDB(t)
+	assert.NoError(t, db.AutoMigrate(&testOptionsModel{}))
+	assert.NoError(t, db.Create(&testOptionsModel{Text: "hello"}).Error)
+
+	v1Policy := NewPolicy[testOptionsModel](endpoints.GET)
+	v1Policy.CanListById(func(c Context, entity testOptionsModel) bool {
+		return true
+	})
+	v1Entries := FromModel[testOptionsModel]("entries", db, v1Policy)
+
+	v2Policy := NewPolicy[testOptionsModel](endpoints.GET)
+	v2Policy.CanListById(func(c Context, entity testOptionsModel) bool {
+		return true
+	})
+	v2Entries := FromModel[testOptionsModel]("entries", db, v2Policy)
+	v2Entries.ReadType(testVersionRead{}, func(entity testOptionsModel) any {
+		return testVersionRead{ID: entity.ID, Body: entity.Text}
+	})
+
+	sunset := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	v1 := NewVersion("v1", &v1Entries).Deprecate(time.Unix(1700000000, 0), sunset, "https://example.com/migrate")
+	v2 := NewVersion("v2", &v2Entries)
+
+	e := echo.New()
+	s, err := New(e, db, []Provider{v1, v2}, WithoutMigrations(), WithVersionHeader("Accept-Version", "v2"))
+	assert.NoError(t, err)
+	assert.Len(t, Models(s.resources), 1)
+
+	get := func(target string, version string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if version != "" {
+			req.Header.Set("Accept-Version", version)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/v1/entries/1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"Text":"hello"`)
+	assert.Equal(t, "@1700000000", rec.Header().Get("Deprecation"))
+	assert.Equal(t, "Tue, 01 Jan 2030 00:00:00 GMT", rec.Header().Get("Sunset"))
+	assert.Equal(t, `<https://example.com/migrate>; rel="deprecation"`, rec.Header().Get("Link"))
+
+	rec = get("/v2/entries/1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id":1,"body":"hello"}`, rec.Body.String())
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+
+	// Without a version in the path, the header selects it, and the default applies without one.
+	rec = get("/entries/1", "v1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "@1700000000", rec.Header().Get("Deprecation"))
+
+	rec = get("/entries/1", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id":1,"body":"hello"}`, rec.Body.String())
+
+	rec = get("/entries/1", "v3")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// The path wins over the header.
+	rec = get("/v2/entries/1", "v1")
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+
+	doc := s.OpenAPI(openapi.Info{Title: "test", Version: "1"})
+	assert.True(t, doc.Paths["/v1/entries/{id}"].Get.Deprecated)
+	assert.Equal(t, "getEntriesV1", doc.Paths["/v1/entries/{id}"].Get.OperationID)
+	assert.Equal(t, "getEntriesV2", doc.Paths["/v2/entries/{id}"].Get.OperationID)
+
+	encoded, err := json.Marshal(doc.Paths["/v2/entries/{id}"].Get.Responses["200"])
+	assert.NoError(t, err)
+	assert.Contains(t, string(encoded), "testVersionRead")
+}
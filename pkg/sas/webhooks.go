@@ -0,0 +1,543 @@
+package sas
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/imthatgin/sas/pkg/migration"
+	"gorm.io/gorm"
+)
+
+const (
+	// WebhookTableName is the table webhook subscriptions are stored in.
+	WebhookTableName = "__sas_webhooks"
+
+	// WebhookDeliveryTableName is the table the deliveries of events to webhooks are logged in.
+	WebhookDeliveryTableName = "__sas_webhook_deliveries"
+
+	// WebhookMigrationName is the name of the system migration which creates the webhook tables.
+	WebhookMigrationName = "000_sas_webhooks"
+
+	// WebhookSignatureHeader holds the HMAC-SHA256 of the request body with the secret of the webhook,
+	// as sha256=<hex>.
+	WebhookSignatureHeader = "X-Sas-Signature"
+)
+
+// Webhook subscribes a URL to the events of a resource. Events is a comma separated list of event types,
+// such as created,deleted, and an empty list subscribes to every type.
+// A webhook only receives the events of its tenant. Like the models embedding TenantModel, webhooks are owned by
+// the tenant of the request which created them, so tenants only see their own. Webhooks created without a tenant
+// receive the events of the resources without tenancy.
+type Webhook struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	URL      string `gorm:"size:2048" json:"url"`
+	Resource string `gorm:"size:255;index" json:"resource"`
+	Events   string `gorm:"size:255" json:"events"`
+	TenantID string `gorm:"size:255;index" json:"tenantId,omitempty"`
+	// Secret is never encoded, so it is left out of responses, events, audit records and history.
+	Secret string `gorm:"size:255" json:"-"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (Webhook) TableName() string {
+	return WebhookTableName
+}
+
+func (w *Webhook) tenantID() *string {
+	return &w.TenantID
+}
+
+func (w *Webhook) tenantRequired() bool {
+	return false
+}
+
+// WebhookRequest is the body which creates or updates a webhook through the resource of Webhooks.Resource.
+type WebhookRequest struct {
+	URL      string `json:"url" validate:"required,url"`
+	Resource string `json:"resource" validate:"required"`
+	Events   string `json:"events"`
+	Secret   string `json:"secret"`
+}
+
+// Accepts reports whether the webhook is subscribed to events of the type.
+func (w Webhook) Accepts(eventType EventType) bool {
+	if strings.TrimSpace(w.Events) == "" {
+		return true
+	}
+
+	for _, accepted := range strings.Split(w.Events, ",") {
+		if EventType(strings.TrimSpace(accepted)) == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WebhookStatus is the state of a delivery.
+type WebhookStatus string
+
+const (
+	WebhookPending   WebhookStatus = "pending"
+	WebhookDelivered WebhookStatus = "delivered"
+	// WebhookDead is the dead-letter state of deliveries which failed every attempt.
+	WebhookDead WebhookStatus = "dead"
+)
+
+// WebhookDelivery is a single event sent to a webhook, and the result of its latest attempt.
+type WebhookDelivery struct {
+	ID        uint `gorm:"primaryKey" json:"id"`
+	WebhookID uint `gorm:"index" json:"webhookId"`
+
+	Resource  string          `gorm:"size:255" json:"resource"`
+	Tenant    string          `gorm:"size:255" json:"tenant,omitempty"`
+	EventType EventType       `gorm:"size:32" json:"eventType"`
+	EntityID  uint            `json:"entityId"`
+	Payload   json.RawMessage `json:"payload"`
+
+	Status         WebhookStatus `gorm:"size:32;index:idx_sas_webhook_deliveries_due" json:"status"`
+	Attempts       int           `json:"attempts"`
+	NextAttemptAt  time.Time     `gorm:"index:idx_sas_webhook_deliveries_due" json:"nextAttemptAt"`
+	ResponseStatus int           `json:"responseStatus,omitempty"`
+	LastError      string        `json:"lastError,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return WebhookDeliveryTableName
+}
+
+// WebhookOptions configures the delivery of webhooks. Zero fields use the value of DefaultWebhookOptions.
+type WebhookOptions struct {
+	// MaxAttempts is the number of attempts after which a delivery is dead.
+	MaxAttempts int
+	// InitialBackoff is the wait after the first failed attempt, which doubles with every attempt up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// Timeout limits each request.
+	Timeout time.Duration
+	// PollInterval is how often the worker looks for due deliveries, besides when events are enqueued.
+	PollInterval time.Duration
+	// BatchSize is the number of deliveries attempted per poll.
+	BatchSize int
+}
+
+// DefaultWebhookOptions are used for the zero fields of the options given to NewWebhooks.
+var DefaultWebhookOptions = WebhookOptions{
+	MaxAttempts:    8,
+	InitialBackoff: 10 * time.Second,
+	MaxBackoff:     time.Hour,
+	Timeout:        10 * time.Second,
+	PollInterval:   5 * time.Second,
+	BatchSize:      50,
+}
+
+// Webhooks delivers the events of resources to the webhooks subscribed to them. Events are logged as pending
+// deliveries in the transaction of their change, and sent by the worker started with Start. Deliveries are at least
+// once, so receivers should deduplicate on the X-Sas-Delivery header.
+type Webhooks struct {
+	db      *gorm.DB
+	options WebhookOptions
+	client  *http.Client
+	logger  Logger
+
+	validateURL func(u *url.URL) error
+
+	wake chan struct{}
+	now  func() time.Time
+}
+
+// NewWebhooks creates webhooks stored in db.
+func NewWebhooks(db *gorm.DB, options WebhookOptions) *Webhooks {
+	defaults := DefaultWebhookOptions
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = defaults.MaxAttempts
+	}
+	if options.InitialBackoff <= 0 {
+		options.InitialBackoff = defaults.InitialBackoff
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = defaults.MaxBackoff
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaults.Timeout
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = defaults.PollInterval
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaults.BatchSize
+	}
+
+	return &Webhooks{
+		db:      db,
+		options: options,
+		client:  &http.Client{Timeout: options.Timeout},
+
+		wake: make(chan struct{}, 1),
+		now:  time.Now,
+	}
+}
+
+// Client replaces the HTTP client deliveries are sent with.
+func (w *Webhooks) Client(client *http.Client) *Webhooks {
+	w.client = client
+	return w
+}
+
+// URLValidator sets a check for the URLs of webhooks, which runs when they are created or changed through Resource,
+// and before every delivery. By default any http or https URL is allowed, including the addresses of internal
+// services, so servers where untrusted callers create webhooks should restrict them, such as with
+// AllowWebhookHosts.
+func (w *Webhooks) URLValidator(validate func(u *url.URL) error) *Webhooks {
+	w.validateURL = validate
+	return w
+}
+
+// AllowWebhookHosts returns a URL validator which only allows the given hosts, such as hooks.example.com.
+func AllowWebhookHosts(hosts ...string) func(u *url.URL) error {
+	return func(u *url.URL) error {
+		if !slices.Contains(hosts, u.Hostname()) {
+			return fmt.Errorf("webhooks to %q are not allowed", u.Hostname())
+		}
+
+		return nil
+	}
+}
+
+// checkURL returns an error unless deliveries may be sent to raw.
+func (w *Webhooks) checkURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return errors.Join(ErrorResourceInvalidData, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Join(ErrorResourceInvalidData, fmt.Errorf("webhook URL %q is not an http or https URL", raw))
+	}
+	if w.validateURL != nil {
+		if err := w.validateURL(u); err != nil {
+			return errors.Join(ErrorResourceInvalidData, err)
+		}
+	}
+
+	return nil
+}
+
+// Logger sets the logger the worker reports errors to. New sets the logger of the server on the webhooks given
+// to WithWebhooks, unless they have their own.
+func (w *Webhooks) Logger(logger Logger) *Webhooks {
+	w.logger = logger
+	return w
+}
+
+// RegisterMigration adds the system migration which creates the webhook tables to m, unless it already has it.
+func (w *Webhooks) RegisterMigration(m *migration.Migrator) {
+	for _, existing := range m.Migrations() {
+		if existing.Name == WebhookMigrationName {
+			return
+		}
+	}
+
+	m.RegisterSystemMigration(WebhookMigrationName, func(db *gorm.DB) error {
+		return db.AutoMigrate(&Webhook{}, &WebhookDelivery{})
+	}, func(db *gorm.DB) error {
+		return db.Migrator().DropTable(&WebhookDelivery{}, &Webhook{})
+	})
+}
+
+// Resource creates a resource managing the webhook subscriptions, which never returns their secrets.
+// The URLs of the webhooks are checked with the URL validator. Webhooks can not be created in bulk.
+func (w *Webhooks) Resource(name string, policy Policy[Webhook]) ModelResource[Webhook] {
+	mr := FromModel[Webhook](name, w.db, policy)
+	mr.CreateBindType(WebhookRequest{})
+	mr.WriteBindType(WebhookRequest{})
+
+	mr.createTransformer = func(c Context) (*Webhook, error) {
+		var request WebhookRequest
+		if err := c.Bind(&request); err != nil {
+			return nil, err
+		}
+		if err := validate(c, &request); err != nil {
+			return nil, err
+		}
+		if err := w.checkURL(request.URL); err != nil {
+			return nil, err
+		}
+
+		return &Webhook{URL: request.URL, Resource: request.Resource, Events: request.Events, Secret: request.Secret}, nil
+	}
+
+	write := mr.Queries.writeByIdQuery
+	mr.Queries.WriteByIdQuery(func(c Context, q *gorm.DB, entity *Webhook, new any) error {
+		if request, ok := new.(*WebhookRequest); ok && request.URL != "" {
+			if err := w.checkURL(request.URL); err != nil {
+				return err
+			}
+		}
+
+		return write(c, q, entity, new)
+	})
+
+	return mr
+}
+
+// Start runs the worker which sends the due deliveries, until ctx is done.
+func (w *Webhooks) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(w.options.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := w.DeliverPending(ctx); err != nil {
+				orGlobalLogger(w.logger).Errorf("Could not deliver webhooks: %s", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-w.wake:
+			}
+		}
+	}()
+}
+
+// DeliverPending attempts every pending delivery which is due, up to the batch size. It is called by the worker,
+// and can be called directly instead of starting it.
+func (w *Webhooks) DeliverPending(ctx context.Context) error {
+	var deliveries []WebhookDelivery
+	err := w.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", WebhookPending, w.now().UTC()).
+		Order("next_attempt_at").
+		Limit(w.options.BatchSize).
+		Find(&deliveries).Error
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		if err := w.attempt(ctx, delivery); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Redeliver moves a delivery, such as a dead one, back to pending and due now.
+func (w *Webhooks) Redeliver(deliveryID uint) error {
+	return w.db.Model(&WebhookDelivery{}).Where("id = ?", deliveryID).Updates(map[string]any{
+		"status":          WebhookPending,
+		"next_attempt_at": w.now().UTC(),
+	}).Error
+}
+
+func (w *Webhooks) attempt(ctx context.Context, delivery WebhookDelivery) error {
+	var hook Webhook
+	err := w.db.WithContext(ctx).First(&hook, delivery.WebhookID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		delivery.Status = WebhookDead
+		delivery.LastError = "webhook was removed"
+		return w.db.Save(&delivery).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	// Webhooks stored before the URL validator was set, or changed without Resource, are never sent to.
+	if err := w.checkURL(hook.URL); err != nil {
+		delivery.Status = WebhookDead
+		delivery.LastError = err.Error()
+		return w.db.Save(&delivery).Error
+	}
+
+	delivery.Attempts++
+	delivery.ResponseStatus, err = w.send(ctx, hook, delivery)
+	switch {
+	case err == nil:
+		delivery.Status = WebhookDelivered
+		delivery.LastError = ""
+	case delivery.Attempts >= w.options.MaxAttempts:
+		delivery.Status = WebhookDead
+		delivery.LastError = err.Error()
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = w.now().UTC().Add(w.backoff(delivery.Attempts))
+	}
+
+	return w.db.Save(&delivery).Error
+}
+
+// backoff returns the wait after the given number of failed attempts.
+func (w *Webhooks) backoff(attempts int) time.Duration {
+	backoff := w.options.InitialBackoff
+	for i := 1; i < attempts && backoff < w.options.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, w.options.MaxBackoff)
+}
+
+func (w *Webhooks) send(ctx context.Context, hook Webhook, delivery WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sas-Event", string(delivery.EventType))
+	req.Header.Set("X-Sas-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	if hook.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, WebhookSignature(hook.Secret, delivery.Payload))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// WebhookSignature returns the value of the signature header for a body, for receivers to compare against
+// with hmac.Equal.
+func WebhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// enqueue logs a pending delivery of event in tx, for every webhook of its tenant subscribed to it.
+func (w *Webhooks) enqueue(tx *gorm.DB, event Event) error {
+	var hooks []Webhook
+	if err := tx.Where("resource = ? AND tenant_id = ?", event.Resource, event.Tenant).Find(&hooks).Error; err != nil {
+		return err
+	}
+
+	hooks = slices.DeleteFunc(hooks, func(hook Webhook) bool {
+		return !hook.Accepts(event.Type)
+	})
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]WebhookDelivery, len(hooks))
+	for i, hook := range hooks {
+		deliveries[i] = WebhookDelivery{
+			WebhookID:     hook.ID,
+			Resource:      event.Resource,
+			Tenant:        event.Tenant,
+			EventType:     event.Type,
+			EntityID:      event.EntityID,
+			Payload:       payload,
+			Status:        WebhookPending,
+			NextAttemptAt: w.now().UTC(),
+		}
+	}
+	return tx.Create(&deliveries).Error
+}
+
+// notify wakes the worker, once a change with deliveries has been committed.
+func (w *Webhooks) notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// WebhookDeliveriesEndpoint returns a read-only handler listing the deliveries in db, newest first. The webhook
+// and status query parameters filter the deliveries, and limit and offset paginate them. A nil can denies every call.
+func WebhookDeliveriesEndpoint(db *gorm.DB, can func(c Context) bool) HandlerFunc {
+	return func(c Context) error {
+		if can == nil || !can(c) {
+			return ErrorResourceNoAccess
+		}
+
+		q := db.Model(&WebhookDelivery{}).Order("id DESC")
+		if webhook := c.QueryParam("webhook"); webhook != "" {
+			id, err := strconv.ParseUint(webhook, 10, 0)
+			if err != nil {
+				return errors.Join(ErrorResourceInvalidID, err)
+			}
+			q = q.Where("webhook_id = ?", id)
+		}
+		if status := c.QueryParam("status"); status != "" {
+			q = q.Where("status = ?", status)
+		}
+
+		q, err := Paginate(c, q)
+		if err != nil {
+			return err
+		}
+
+		deliveries := []WebhookDelivery{}
+		if err := q.Find(&deliveries).Error; err != nil {
+			return errors.Join(ErrorDatabaseIssue, err)
+		}
+
+		return c.JSON(http.StatusOK, deliveries)
+	}
+}
+
+// Webhooks sends the creates, writes and deletes of the resource to the webhooks subscribed to it.
+// The webhook tables are created by the migration added with Webhooks.RegisterMigration, or by WithWebhooks.
+func (mr *ModelResource[T]) Webhooks(webhooks *Webhooks) {
+	mr.webhooks = webhooks
+}
+
+// webhooksByDefault enables webhooks for the resource, unless it has its own.
+func (mr *ModelResource[T]) webhooksByDefault(webhooks *Webhooks) {
+	if mr.webhooks == nil {
+		mr.webhooks = webhooks
+	}
+}
+
+// writeWebhooks logs the deliveries of the event of a change in tx, if the resource has webhooks.
+func (mr *ModelResource[T]) writeWebhooks(c Context, tx *gorm.DB, action Operation, before *T, after *T) error {
+	if mr.webhooks == nil {
+		return nil
+	}
+
+	event, ok := mr.newEvent(c, action, before, after)
+	if !ok {
+		return nil
+	}
+
+	return mr.webhooks.enqueue(tx, event)
+}
+
+// ServeWebhookDeliveries serves the delivery log read-only at path under the base path, such as
+// /_sas/webhooks/deliveries. See WebhookDeliveriesEndpoint.
+func (s *Server) ServeWebhookDeliveries(path string, can func(c Context) bool) {
+	s.serveGet(path, WebhookDeliveriesEndpoint(s.db, can))
+}
@@ -0,0 +1,183 @@
+package sas
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/imthatgin/sas/pkg/migration"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestWebhooks(t *testing.T) {
+	var mu sync.Mutex
+	var received []*http.Request
+	var bodies []string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		received = append(received, r)
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer receiver.Close()
+
+	db, resource := newTestEntries(t, endpoints.AllEndpoints)
+	webhooks := NewWebhooks(db, WebhookOptions{MaxAttempts: 2, InitialBackoff: time.Minute})
+
+	s := newTestServer(t, db, []Provider{&resource}, WithMigrations(migration.NewMigrator(), true), WithWebhooks(webhooks))
+	s.ServeWebhookDeliveries("/_sas/webhooks/deliveries", func(c Context) bool { return true })
+
+	assert.NoError(t, db.Create(&[]Webhook{
+		{URL: receiver.URL + "/ok", Resource: "entries", Events: "created", Secret: "secret"},
+		{URL: receiver.URL + "/fail", Resource: "entries"},
+		{URL: receiver.URL + "/other", Resource: "other"},
+	}).Error)
+
+	assert.Equal(t, http.StatusOK, s.serve(http.MethodPost, "/entries", `{"Text":"first"}`).Code)
+	assert.NoError(t, webhooks.DeliverPending(context.Background()))
+
+	assert.Len(t, received, 2)
+	ok := received[0]
+	if ok.URL.Path != "/ok" {
+		ok, bodies[0] = received[1], bodies[1]
+	}
+	assert.Equal(t, "created", ok.Header.Get("X-Sas-Event"))
+	assert.Equal(t, WebhookSignature("secret", []byte(bodies[0])), ok.Header.Get(WebhookSignatureHeader))
+	assert.Contains(t, bodies[0], `"Text":"first"`)
+
+	var failed WebhookDelivery
+	assert.NoError(t, db.Where("webhook_id = ?", 2).First(&failed).Error)
+	assert.Equal(t, WebhookPending, failed.Status)
+	assert.Equal(t, 1, failed.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, failed.ResponseStatus)
+
+	// The failed delivery is not due again until its backoff has passed.
+	assert.NoError(t, webhooks.DeliverPending(context.Background()))
+	assert.Len(t, received, 2)
+
+	webhooks.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	assert.NoError(t, webhooks.DeliverPending(context.Background()))
+	assert.Len(t, received, 3)
+
+	// Updates are only sent to the webhook without an event filter.
+	assert.Equal(t, http.StatusOK, s.serve(http.MethodPut, "/entries/1", `{"Text":"second"}`).Code)
+	var count int64
+	assert.NoError(t, db.Model(&WebhookDelivery{}).Count(&count).Error)
+	assert.Equal(t, int64(3), count)
+
+	rec := s.serve(http.MethodGet, "/_sas/webhooks/deliveries?status=dead", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var dead []WebhookDelivery
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &dead))
+	assert.Len(t, dead, 1)
+	assert.Equal(t, uint(2), dead[0].WebhookID)
+	assert.Equal(t, 2, dead[0].Attempts)
+
+	assert.NoError(t, webhooks.Redeliver(dead[0].ID))
+	assert.NoError(t, webhooks.DeliverPending(context.Background()))
+	assert.Len(t, received, 5)
+
+	// The deliveries are logged in the transaction of the change, so neither is kept without the other.
+	assert.NoError(t, db.Callback().Create().Before("gorm:create").Register("fail_deliveries", func(tx *gorm.DB) {
+		if tx.Statement.Table == WebhookDeliveryTableName {
+			_ = tx.AddError(errors.New("deliveries could not be logged"))
+		}
+	}))
+	assert.Equal(t, http.StatusInternalServerError, s.serve(http.MethodPost, "/entries", `{"Text":"lost"}`).Code)
+	assert.Error(t, db.Where("text = ?", "lost").First(&testOptionsModel{}).Error)
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	webhooks := NewWebhooks(nil, WebhookOptions{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second})
+
+	assert.Equal(t, time.Second, webhooks.backoff(1))
+	assert.Equal(t, 4*time.Second, webhooks.backoff(3))
+	assert.Equal(t, 5*time.Second, webhooks.backoff(10))
+}
+
+func TestWebhookSecretIsNeverEncoded(t *testing.T) {
+	db := newTestOptionsDB(t)
+
+	policy := NewPolicy[Webhook](endpoints.AllEndpoints)
+	policy.
+		CanListAll(func(c Context) bool { return true }).
+		CanCreate(func(c Context) bool { return true }).
+		CanListById(func(c Context, entity Webhook) bool { return true }).
+		CanWriteById(func(c Context, entity Webhook) bool { return true }).
+		CanDeleteById(func(c Context, entity Webhook) bool { return true }).
+		CanListHistory(func(c Context, entity Webhook) bool { return true })
+
+	webhooks := NewWebhooks(db, DefaultWebhookOptions)
+	resource := webhooks.Resource("webhooks", policy)
+	resource.History()
+
+	s := newTestServer(t, db, []Provider{&resource}, WithMigrations(migration.NewMigrator(), true), WithAuditor(NewAuditor()), WithWebhooks(webhooks))
+	s.ServeAudit("/_sas/audit", func(c Context) bool { return true })
+
+	assert.Equal(t, http.StatusOK, s.serve(http.MethodPost, "/webhooks", `{"url":"http://example.com","resource":"entries","secret":"hunter2"}`).Code)
+	assert.Equal(t, http.StatusOK, s.serve(http.MethodPut, "/webhooks/1", `{"url":"http://example.com/changed","resource":"entries"}`).Code)
+
+	var stored Webhook
+	assert.NoError(t, db.First(&stored, 1).Error)
+	assert.Equal(t, "hunter2", stored.Secret)
+
+	for _, target := range []string{"/webhooks", "/webhooks/1", "/webhooks/1/history", "/webhooks/1/history/1", "/_sas/audit"} {
+		rec := s.serve(http.MethodGet, target, "")
+		assert.Equal(t, http.StatusOK, rec.Code, target)
+		assert.NotContains(t, rec.Body.String(), "hunter2", target)
+	}
+
+	assert.Equal(t, http.StatusOK, s.serve(http.MethodDelete, "/webhooks/1", "").Code)
+	rec := s.serve(http.MethodGet, "/_sas/audit", "")
+	assert.Contains(t, rec.Body.String(), `"delete"`)
+	assert.NotContains(t, rec.Body.String(), "hunter2")
+}
+
+func TestWebhookURLValidator(t *testing.T) {
+	db := newTestOptionsDB(t)
+
+	policy := NewPolicy[Webhook](endpoints.AllEndpoints | endpoints.BULK)
+	policy.
+		CanCreate(func(c Context) bool { return true }).
+		CanListById(func(c Context, entity Webhook) bool { return true }).
+		CanWriteById(func(c Context, entity Webhook) bool { return true })
+
+	webhooks := NewWebhooks(db, DefaultWebhookOptions).URLValidator(AllowWebhookHosts("hooks.example.com"))
+	resource := webhooks.Resource("webhooks", policy)
+
+	s := newTestServer(t, db, []Provider{&resource}, WithMigrations(migration.NewMigrator(), true), WithWebhooks(webhooks))
+	serve := func(method string, target string, body string) int {
+		return s.serve(method, target, body).Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/webhooks", `{"url":"http://169.254.169.254/latest","resource":"entries"}`))
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/webhooks", `{"url":"file:///etc/passwd","resource":"entries"}`))
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/webhooks", `{"url":"https://hooks.example.com/sas","resource":"entries"}`))
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPut, "/webhooks/1", `{"url":"http://localhost/admin","resource":"entries"}`))
+	assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/webhooks/bulk", `[{"url":"http://localhost/admin","resource":"entries"}]`))
+
+	// Webhooks which were stored without being checked are not sent to.
+	assert.NoError(t, db.Create(&Webhook{URL: "http://localhost/admin", Resource: "entries"}).Error)
+	assert.NoError(t, db.Create(&WebhookDelivery{WebhookID: 2, Status: WebhookPending, Payload: json.RawMessage("{}")}).Error)
+	assert.NoError(t, webhooks.DeliverPending(context.Background()))
+
+	var delivery WebhookDelivery
+	assert.NoError(t, db.First(&delivery).Error)
+	assert.Equal(t, WebhookDead, delivery.Status)
+	assert.Equal(t, 0, delivery.Attempts)
+}
@@ -0,0 +1,29 @@
+// Package saschi registers sas resources on a chi router.
+package saschi
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/imthatgin/sas/pkg/sas"
+	"github.com/imthatgin/sas/pkg/sashttp"
+)
+
+// NewRouter adapts a chi router to sas.Router. It accepts the same options as the sashttp package.
+func NewRouter(r chi.Router, options ...sashttp.Option) sas.Router {
+	options = append([]sashttp.Option{sashttp.WithParam(chi.URLParam)}, options...)
+
+	return sashttp.NewRouter(func(method string, pattern string, handler http.Handler) {
+		r.Method(method, pattern, handler)
+	}, options...)
+}
+
+// Register registers the resources on a chi router.
+func Register(r chi.Router, resources []sas.Provider, options ...sashttp.Option) sas.Router {
+	router := NewRouter(r, options...)
+	for _, resource := range resources {
+		resource.Register(router)
+	}
+
+	return router
+}
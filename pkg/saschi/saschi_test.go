@@ -0,0 +1,57 @@
+package saschi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/imthatgin/sas/pkg/sas"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type note struct {
+	sas.DefaultModel
+
+	Text string `json:"text"`
+}
+
+func TestRegister(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	assert.NoError(t, err)
+
+	// Every connection to :memory: is a separate database, so only one may be used.
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	assert.NoError(t, db.AutoMigrate(&note{}))
+	assert.NoError(t, db.Create(&note{Text: "hello"}).Error)
+
+	policy := sas.NewPolicy[note](endpoints.GET | endpoints.DELETE)
+	policy.
+		CanListById(func(c sas.Context, entity note) bool { return true }).
+		CanDeleteById(func(c sas.Context, entity note) bool { return false })
+
+	notes := sas.FromModel[note]("notes", db, policy)
+
+	r := chi.NewRouter()
+	r.Route("/api", func(r chi.Router) {
+		Register(r, []sas.Provider{&notes})
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/notes/1", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"text":"hello"`)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/notes/1", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/notes", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
@@ -0,0 +1,350 @@
+package sasclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/imthatgin/sas/pkg/sas"
+)
+
+// Client holds the connection settings shared by every Resource.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	header     http.Header
+}
+
+// Option configures a Client.
+type Option func(c *Client)
+
+// WithHTTPClient sets the http.Client used for requests, instead of http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithHeader adds a header to every request, such as Authorization.
+func WithHeader(key string, value string) Option {
+	return func(c *Client) {
+		c.header.Add(key, value)
+	}
+}
+
+// New creates a client for the sas server at baseURL, such as https://api.example.com.
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		header:     http.Header{},
+	}
+
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
+}
+
+// Error is returned for any response which is not successful.
+// It unwraps to the matching sas error, such as sas.ErrorResourceNotFound, when the server reported one.
+type Error struct {
+	StatusCode int
+	Message    string
+
+	err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("sas request failed with status %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// knownErrors are the errors which sas.ManagedModelErrorHandler writes as the response body.
+var knownErrors = func() []error {
+	var errs []error
+	for _, status := range sas.ErrorStatusCodes {
+		errs = append(errs, status.Err)
+	}
+	return errs
+}()
+
+// do sends a request, and decodes a JSON response into out when it is not nil.
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body any, out any) error {
+	resp, err := c.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return decodeError(resp)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *Client) send(ctx context.Context, method string, path string, query url.Values, body any) (*http.Response, error) {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range c.header {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return c.httpClient.Do(req)
+}
+
+func decodeError(resp *http.Response) error {
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	result := &Error{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(message)),
+	}
+
+	for _, known := range knownErrors {
+		if result.Message == known.Error() {
+			result.err = known
+			return result
+		}
+	}
+
+	// Errors not produced by sas, such as from middlewares, are matched by status code only.
+	result.err = statusError(resp.StatusCode)
+
+	return result
+}
+
+func statusError(code int) error {
+	switch code {
+	case http.StatusUnauthorized:
+		return sas.ErrorUnauthenticated
+	case http.StatusForbidden:
+		return sas.ErrorResourceNoAccess
+	case http.StatusNotFound:
+		return sas.ErrorResourceNotFound
+	case http.StatusConflict:
+		return sas.ErrorResourceConflict
+	}
+
+	return nil
+}
+
+// Resource calls the endpoints of a single sas.ModelResource.
+type Resource[T any] struct {
+	client *Client
+	path   string
+}
+
+// NewResource creates a client for the resource registered under name, such as "entries".
+func NewResource[T any](client *Client, name string) *Resource[T] {
+	return &Resource[T]{
+		client: client,
+		path:   "/" + strings.Trim(name, "/"),
+	}
+}
+
+// ListOptions pages through the entities returned by List.
+// sas does not filter lists itself, so there are no typed filters.
+type ListOptions struct {
+	Limit  int
+	Offset int
+
+	// Query is sent as is, for resources which read their own parameters in a custom ListAllQuery.
+	Query url.Values
+}
+
+func (o *ListOptions) values() url.Values {
+	values := url.Values{}
+	if o == nil {
+		return values
+	}
+
+	for key, value := range o.Query {
+		values[key] = append([]string(nil), value...)
+	}
+	if o.Limit > 0 {
+		values.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Offset > 0 {
+		values.Set("offset", strconv.Itoa(o.Offset))
+	}
+
+	return values
+}
+
+// List returns the entities the caller may list. options may be nil.
+func (r *Resource[T]) List(ctx context.Context, options *ListOptions) ([]T, error) {
+	var result []T
+	err := r.client.do(ctx, http.MethodGet, r.path, options.values(), nil, &result)
+
+	return result, err
+}
+
+// Get returns a single entity.
+func (r *Resource[T]) Get(ctx context.Context, id uint) (*T, error) {
+	var result T
+	err := r.client.do(ctx, http.MethodGet, r.itemPath(id), nil, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Create sends body, which should match the create bind type of the resource.
+func (r *Resource[T]) Create(ctx context.Context, body any) error {
+	return r.client.do(ctx, http.MethodPost, r.path, nil, body, nil)
+}
+
+// Update sends body with PUT, which should match the write bind type of the resource.
+func (r *Resource[T]) Update(ctx context.Context, id uint, body any) error {
+	return r.client.do(ctx, http.MethodPut, r.itemPath(id), nil, body, nil)
+}
+
+// Delete removes a single entity.
+func (r *Resource[T]) Delete(ctx context.Context, id uint) error {
+	return r.client.do(ctx, http.MethodDelete, r.itemPath(id), nil, nil, nil)
+}
+
+// BulkCreate creates many entities in a single request, which requires the BULK endpoint on the resource.
+// items should be a slice matching the create bind type. The results are returned also when the request failed.
+func (r *Resource[T]) BulkCreate(ctx context.Context, items any, mode sas.BulkMode) (*sas.BulkResponse, error) {
+	return r.bulk(ctx, http.MethodPost, r.path+"/bulk", bulkQuery(mode), items)
+}
+
+// BulkUpdate partially updates many entities in a single request.
+func (r *Resource[T]) BulkUpdate(ctx context.Context, items []sas.BulkWriteItem, mode sas.BulkMode) (*sas.BulkResponse, error) {
+	return r.bulk(ctx, http.MethodPatch, r.path+"/bulk", bulkQuery(mode), items)
+}
+
+// BulkDelete removes many entities in a single request.
+func (r *Resource[T]) BulkDelete(ctx context.Context, ids []uint, mode sas.BulkMode) (*sas.BulkResponse, error) {
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = strconv.FormatUint(uint64(id), 10)
+	}
+
+	query := bulkQuery(mode)
+	query.Set("ids", strings.Join(idStrings, ","))
+
+	return r.bulk(ctx, http.MethodDelete, r.path, query, nil)
+}
+
+func (r *Resource[T]) bulk(ctx context.Context, method string, path string, query url.Values, body any) (*sas.BulkResponse, error) {
+	resp, err := r.client.send(ctx, method, path, query, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Failed bulk requests still report the result of every item, unless they failed before any item was tried.
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return nil, decodeError(resp)
+	}
+
+	var result sas.BulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &result, &Error{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode), err: statusError(resp.StatusCode)}
+	}
+
+	return &result, nil
+}
+
+func bulkQuery(mode sas.BulkMode) url.Values {
+	query := url.Values{}
+	if mode != "" {
+		query.Set("mode", string(mode))
+	}
+
+	return query
+}
+
+// History returns the prior versions of an entity, oldest first, which requires history on the resource.
+// The data of each entry is the entity encoded as JSON. options may be nil.
+func (r *Resource[T]) History(ctx context.Context, id uint, options *ListOptions) ([]sas.HistoryEntry, error) {
+	var result []sas.HistoryEntry
+	err := r.client.do(ctx, http.MethodGet, r.itemPath(id)+"/history", options.values(), nil, &result)
+
+	return result, err
+}
+
+// HistoryVersion returns an entity as it was at a prior version.
+func (r *Resource[T]) HistoryVersion(ctx context.Context, id uint, version uint) (*T, error) {
+	var result T
+	err := r.client.do(ctx, http.MethodGet, r.itemPath(id)+"/history/"+strconv.FormatUint(uint64(version), 10), nil, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Revert restores an entity to a prior version. The replaced state becomes the next version.
+func (r *Resource[T]) Revert(ctx context.Context, id uint, version uint) error {
+	return r.client.do(ctx, http.MethodPost, r.itemPath(id)+"/revert/"+strconv.FormatUint(uint64(version), 10), nil, nil, nil)
+}
+
+// Action calls a custom action of the resource, such as POST /reports/regenerate.
+// The result is decoded into out when it is not nil.
+func (r *Resource[T]) Action(ctx context.Context, name string, body any, out any) error {
+	return r.client.do(ctx, http.MethodPost, r.path+"/"+strings.Trim(name, "/"), nil, actionBody(body), out)
+}
+
+// ItemAction calls a custom action of an entity, such as POST /orders/1/cancel.
+// The result is decoded into out when it is not nil.
+func (r *Resource[T]) ItemAction(ctx context.Context, id uint, name string, body any, out any) error {
+	return r.client.do(ctx, http.MethodPost, r.itemPath(id)+"/"+strings.Trim(name, "/"), nil, actionBody(body), out)
+}
+
+// actionBody sends an empty object for actions without a request, which every action accepts.
+func actionBody(body any) any {
+	if body == nil {
+		return struct{}{}
+	}
+
+	return body
+}
+
+func (r *Resource[T]) itemPath(id uint) string {
+	return r.path + "/" + strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,133 @@
+package sasclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/imthatgin/sas/pkg/sas"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type note struct {
+	sas.DefaultModel
+
+	Text string
+}
+
+type noteWrite struct {
+	Text string
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	assert.NoError(t, err)
+
+	// Every connection to :memory: is a separate database, so only one may be used.
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	assert.NoError(t, db.AutoMigrate(&note{}))
+
+	policy := sas.NewPolicy[note](endpoints.AllEndpoints)
+	policy.
+		CanListAll(func(c sas.Context) bool { return true }).
+		CanListById(func(c sas.Context, entity note) bool { return entity.Text != "secret" }).
+		CanWriteById(func(c sas.Context, entity note) bool { return true }).
+		CanCreate(func(c sas.Context) bool { return c.Request().Header.Get("X-Test") == "allowed" }).
+		CanDeleteById(func(c sas.Context, entity note) bool { return true })
+
+	notes := sas.FromModel[note]("notes", db, policy)
+	notes.CreateBindType(noteWrite{})
+	notes.WriteBindType(noteWrite{})
+
+	e := echo.New()
+	_, err = sas.New(e, db, []sas.Provider{&notes}, sas.WithoutMigrations())
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestResource(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	denied := NewResource[note](New(server.URL), "notes")
+	assert.ErrorIs(t, denied.Create(ctx, noteWrite{Text: "first"}), sas.ErrorResourceNoAccess)
+
+	notes := NewResource[note](New(server.URL, WithHeader("X-Test", "allowed")), "notes")
+	for _, text := range []string{"first", "second", "secret"} {
+		assert.NoError(t, notes.Create(ctx, noteWrite{Text: text}))
+	}
+
+	all, err := notes.List(ctx, nil)
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	page, err := notes.List(ctx, &ListOptions{Limit: 1, Offset: 1})
+	assert.NoError(t, err)
+	assert.Len(t, page, 1)
+	assert.Equal(t, "second", page[0].Text)
+
+	_, err = notes.List(ctx, &ListOptions{Query: url.Values{"limit": {"many"}}})
+	assert.ErrorIs(t, err, sas.ErrorResourceInvalidData)
+
+	first, err := notes.Get(ctx, all[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", first.Text)
+
+	_, err = notes.Get(ctx, all[2].ID)
+	assert.ErrorIs(t, err, sas.ErrorResourceNoAccess)
+
+	_, err = notes.Get(ctx, 999)
+	assert.ErrorIs(t, err, sas.ErrorResourceNotFound)
+
+	var clientErr *Error
+	assert.ErrorAs(t, err, &clientErr)
+	assert.Equal(t, 404, clientErr.StatusCode)
+
+	assert.NoError(t, notes.Update(ctx, first.ID, noteWrite{Text: "updated"}))
+	first, err = notes.Get(ctx, first.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "updated", first.Text)
+
+	assert.NoError(t, notes.Delete(ctx, first.ID))
+	_, err = notes.Get(ctx, first.ID)
+	assert.ErrorIs(t, err, sas.ErrorResourceNotFound)
+}
+
+func TestDecodeError(t *testing.T) {
+	e := echo.New()
+	e.GET("/:code/:message", func(c echo.Context) error {
+		code, _ := strconv.Atoi(c.Param("code"))
+		return c.String(code, c.Param("message"))
+	})
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+
+	client := New(server.URL)
+	decode := func(code int, message string) error {
+		path := "/" + strconv.Itoa(code) + "/" + url.PathEscape(message)
+		return client.do(context.Background(), http.MethodGet, path, nil, nil, nil)
+	}
+
+	for _, status := range sas.ErrorStatusCodes {
+		assert.ErrorIs(t, decode(status.Code, status.Err.Error()), status.Err)
+	}
+
+	// Responses not written by sas are matched by status code.
+	assert.ErrorIs(t, decode(http.StatusUnauthorized, "login required"), sas.ErrorUnauthenticated)
+	assert.NotErrorIs(t, decode(http.StatusUnauthorized, "login required"), sas.ErrorResourceNoAccess)
+	assert.ErrorIs(t, decode(http.StatusConflict, "changed"), sas.ErrorResourceConflict)
+}
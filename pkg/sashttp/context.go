@@ -0,0 +1,102 @@
+package sashttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/imthatgin/sas/pkg/sas"
+)
+
+// Context implements sas.Context for net/http.
+type Context struct {
+	request *http.Request
+	writer  http.ResponseWriter
+	values  map[string]interface{}
+
+	param func(r *http.Request, name string) string
+}
+
+var _ sas.Context = (*Context)(nil)
+
+// NewContext creates a context for a request. Path parameters are read with http.Request.PathValue.
+func NewContext(w http.ResponseWriter, r *http.Request) *Context {
+	return &Context{
+		request: r,
+		writer:  w,
+		values:  map[string]interface{}{},
+		param: func(r *http.Request, name string) string {
+			return r.PathValue(name)
+		},
+	}
+}
+
+func (c *Context) Request() *http.Request {
+	return c.request
+}
+
+// ResponseWriter returns the writer of the response, such as for setting headers.
+func (c *Context) ResponseWriter() http.ResponseWriter {
+	return c.writer
+}
+
+func (c *Context) Param(name string) string {
+	return c.param(c.request, name)
+}
+
+func (c *Context) QueryParam(name string) string {
+	return c.request.URL.Query().Get(name)
+}
+
+// Bind decodes a JSON request body into i. An empty body leaves i as is.
+func (c *Context) Bind(i interface{}) error {
+	if c.request.Body == nil || c.request.ContentLength == 0 {
+		return nil
+	}
+
+	if contentType := c.request.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "application/json" {
+			return errors.Join(sas.ErrorResourceInvalidData, fmt.Errorf("unsupported content type %q", contentType))
+		}
+	}
+
+	err := json.NewDecoder(c.request.Body).Decode(i)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return errors.Join(sas.ErrorResourceInvalidData, err)
+	}
+
+	return nil
+}
+
+func (c *Context) Get(key string) interface{} {
+	return c.values[key]
+}
+
+func (c *Context) Set(key string, val interface{}) {
+	c.values[key] = val
+}
+
+func (c *Context) JSON(code int, i interface{}) error {
+	c.writer.Header().Set("Content-Type", "application/json")
+	c.writer.WriteHeader(code)
+
+	return json.NewEncoder(c.writer).Encode(i)
+}
+
+func (c *Context) String(code int, s string) error {
+	c.writer.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+	c.writer.WriteHeader(code)
+
+	_, err := io.WriteString(c.writer, s)
+	return err
+}
+
+func (c *Context) NoContent(code int) error {
+	c.writer.WriteHeader(code)
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package sashttp
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/imthatgin/sas/pkg/sas"
+)
+
+var pathParamPattern = regexp.MustCompile(`:(\w+)`)
+
+// HandleFunc registers a handler for a method and a pattern with {name} parameters, such as /entries/{id}.
+type HandleFunc func(method string, pattern string, handler http.Handler)
+
+// ErrorHandler writes the response for an error returned by a handler.
+type ErrorHandler func(err error, w http.ResponseWriter, r *http.Request)
+
+// Option configures a router created with NewRouter or Register.
+type Option func(r *router)
+
+// WithBasePath registers every resource under prefix, such as /api.
+func WithBasePath(prefix string) Option {
+	return func(r *router) {
+		prefix = strings.Trim(prefix, "/")
+		if prefix != "" {
+			r.prefix = "/" + prefix
+		}
+	}
+}
+
+// WithErrorHandler replaces DefaultErrorHandler.
+func WithErrorHandler(handler ErrorHandler) Option {
+	return func(r *router) {
+		r.errorHandler = handler
+	}
+}
+
+// WithMiddlewares adds middlewares to every route, before the middlewares of the resources.
+func WithMiddlewares(middlewares ...sas.MiddlewareFunc) Option {
+	return func(r *router) {
+		r.middlewares = append(r.middlewares, middlewares...)
+	}
+}
+
+// WithParam sets how path parameters are read, for routers which do not set http.Request.PathValue.
+func WithParam(param func(r *http.Request, name string) string) Option {
+	return func(r *router) {
+		r.param = param
+	}
+}
+
+// DefaultErrorHandler writes the sas errors with a matching status code, and status 500 for any other error.
+func DefaultErrorHandler(err error, w http.ResponseWriter, r *http.Request) {
+	code, message, _ := sas.ErrorStatus(err)
+
+	w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+	w.WriteHeader(code)
+	_, _ = w.Write([]byte(message))
+}
+
+// Register registers the resources on a net/http ServeMux, using the method patterns of Go 1.22.
+func Register(mux *http.ServeMux, resources []sas.Provider, options ...Option) sas.Router {
+	r := NewRouter(func(method string, pattern string, handler http.Handler) {
+		mux.Handle(method+" "+pattern, handler)
+	}, options...)
+
+	for _, resource := range resources {
+		resource.Register(r)
+	}
+
+	return r
+}
+
+// NewRouter adapts any net/http router to sas.Router, through the function which registers a handler.
+func NewRouter(handle HandleFunc, options ...Option) sas.Router {
+	r := &router{
+		handle:       handle,
+		errorHandler: DefaultErrorHandler,
+	}
+
+	for _, option := range options {
+		option(r)
+	}
+
+	return r
+}
+
+type router struct {
+	handle       HandleFunc
+	prefix       string
+	middlewares  []sas.MiddlewareFunc
+	errorHandler ErrorHandler
+	param        func(r *http.Request, name string) string
+}
+
+func (r *router) Add(method string, path string, handler sas.HandlerFunc, middleware ...sas.MiddlewareFunc) {
+	handler = sas.Chain(handler, append(append([]sas.MiddlewareFunc{}, r.middlewares...), middleware...)...)
+
+	pattern := pathParamPattern.ReplaceAllString(r.prefix+path, "{$1}")
+	if pattern == "" {
+		pattern = "/"
+	}
+
+	r.handle(method, pattern, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		c := NewContext(w, req)
+		if r.param != nil {
+			c.param = r.param
+		}
+
+		if err := handler(c); err != nil {
+			r.errorHandler(err, w, req)
+		}
+	}))
+}
+
+func (r *router) Group(prefix string, middleware ...sas.MiddlewareFunc) sas.Router {
+	group := *r
+	group.prefix = r.prefix + prefix
+	group.middlewares = append(append([]sas.MiddlewareFunc{}, r.middlewares...), middleware...)
+
+	return &group
+}
@@ -0,0 +1,95 @@
+package sashttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/imthatgin/sas/pkg/sas"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type note struct {
+	sas.DefaultModel
+
+	Text string `json:"text"`
+}
+
+type noteCreate struct {
+	Text string `json:"text"`
+}
+
+func TestRegister(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"))
+	assert.NoError(t, err)
+
+	// Every connection to :memory: is a separate database, so only one may be used.
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	assert.NoError(t, db.AutoMigrate(&note{}))
+
+	policy := sas.NewPolicy[note](endpoints.AllEndpoints)
+	policy.
+		CanListAll(func(c sas.Context) bool { return true }).
+		CanListById(func(c sas.Context, entity note) bool { return entity.Text != "secret" }).
+		CanCreate(func(c sas.Context) bool { return c.Request().Header.Get("Authorization") != "" })
+
+	notes := sas.FromModel[note]("notes", db, policy)
+	notes.CreateBindType(noteCreate{})
+
+	var seen []string
+	mux := http.NewServeMux()
+	Register(mux, []sas.Provider{&notes}, WithBasePath("/api"), WithMiddlewares(func(next sas.HandlerFunc) sas.HandlerFunc {
+		return func(c sas.Context) error {
+			seen = append(seen, c.Request().Method)
+			sas.ResponseHeader(c).Set("X-Sas", "yes")
+			return next(c)
+		}
+	}))
+
+	serve := func(method string, target string, body string, authorized bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if authorized {
+			req.Header.Set("Authorization", "Bearer test")
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodPost, "/api/notes", `{"text":"hello"}`, false)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, sas.ErrorResourceNoAccess.Error(), rec.Body.String())
+
+	rec = serve(http.MethodPost, "/api/notes", `{"text":"hello"}`, true)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "yes", rec.Header().Get("X-Sas"))
+	assert.NoError(t, db.Create(&note{Text: "secret"}).Error)
+
+	rec = serve(http.MethodGet, "/api/notes/1", "", false)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"text":"hello"`)
+
+	rec = serve(http.MethodGet, "/api/notes/2", "", false)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = serve(http.MethodGet, "/api/notes/3", "", false)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serve(http.MethodGet, "/api/notes/abc", "", false)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(http.MethodGet, "/api/notes?limit=1", "", false)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1, strings.Count(rec.Body.String(), `"text"`))
+
+	assert.Equal(t, []string{"POST", "POST", "GET", "GET", "GET", "GET", "GET"}, seen)
+}
@@ -0,0 +1,493 @@
+package tsgen
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/imthatgin/sas/pkg/openapi"
+	"github.com/imthatgin/sas/pkg/sas"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	identifierPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+	nonAlphanumeric   = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+const (
+	TypesFile  = "types.ts"
+	ClientFile = "client.ts"
+	IndexFile  = "index.ts"
+)
+
+const header = "// Code generated by sas tsgen. DO NOT EDIT.\n\n"
+
+// Generate writes TypeScript types and a fetch based client for the resources into dir.
+// Only resources implementing sas.Describer, such as sas.ModelResource, are included, also when they are part of a
+// sas.Version.
+func Generate(resources []sas.Provider, dir string) error {
+	files := Render(resources)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(files[name]), 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Command runs the generator from command line arguments, which are "[-out DIR]" after the command name.
+// It is meant to be embedded in an application, which is the only place the resource types are known.
+func Command(resources []sas.Provider, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("tsgen", flag.ContinueOnError)
+	flags.SetOutput(out)
+	dir := flags.String("out", "sas-client", "directory to write the TypeScript files to")
+
+	if len(args) > 0 {
+		args = args[1:]
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New("tsgen does not take positional arguments")
+	}
+
+	if err := Generate(resources, *dir); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintln(out, "Generated TypeScript client in", *dir)
+	return nil
+}
+
+// Render returns the generated files by name, without writing them.
+func Render(resources []sas.Provider) map[string]string {
+	g := &generator{
+		names: map[reflect.Type]string{},
+		used:  map[string]bool{},
+	}
+
+	var client strings.Builder
+	client.WriteString(header)
+	client.WriteString(clientRuntime)
+
+	var imports []string
+	for _, description := range sas.Describe(resources) {
+		imports = append(imports, g.writeResource(&client, description)...)
+	}
+
+	var types strings.Builder
+	types.WriteString(header)
+	types.WriteString("/** An ISO 8601 timestamp, as encoded by Go's time.Time. */\nexport type DateTime = string;\n")
+	for _, declaration := range g.declarations {
+		types.WriteString("\n")
+		types.WriteString(declaration)
+	}
+
+	if g.useBulk {
+		client.WriteString(bulkRuntime)
+	}
+	if g.useHistory {
+		client.WriteString(historyRuntime)
+	}
+
+	clientContent := client.String()
+	if len(imports) > 0 {
+		clientContent = strings.Replace(clientContent, "// imports\n",
+			"import type { "+strings.Join(uniqueSorted(imports), ", ")+" } from \"./types\";\n", 1)
+	} else {
+		clientContent = strings.Replace(clientContent, "// imports\n", "", 1)
+	}
+
+	return map[string]string{
+		TypesFile:  types.String(),
+		ClientFile: clientContent,
+		IndexFile:  header + "export * from \"./types\";\nexport * from \"./client\";\n",
+	}
+}
+
+type generator struct {
+	names        map[reflect.Type]string
+	used         map[string]bool
+	declarations []string
+
+	// useBulk is set when a resource has bulk endpoints, which need the bulk runtime.
+	useBulk bool
+	// useHistory is set when a resource keeps history, which needs the HistoryEntry type.
+	useHistory bool
+}
+
+// writeResource writes the client class of a resource, and returns the type names it uses.
+func (g *generator) writeResource(out *strings.Builder, description sas.ResourceDescription) []string {
+	resourceName := pascalCase(strings.Trim(description.Name, "/")) + pascalCase(description.Version)
+	path := "/" + strings.Trim(description.Name, "/")
+	if description.Version != "" {
+		path = "/" + description.Version + path
+	}
+
+	modelName := g.named(description.Model, "")
+	if description.ReadType != nil {
+		modelName = g.named(description.ReadType, resourceName+"Read")
+	}
+	imports := []string{modelName}
+
+	createType := "unknown"
+	if description.CreateBindType != nil {
+		createType = g.named(description.CreateBindType, resourceName+"Create")
+		imports = append(imports, createType)
+	}
+
+	writeType := "unknown"
+	if description.WriteBindType != nil {
+		writeType = g.named(description.WriteBindType, resourceName+"Write")
+		imports = append(imports, writeType)
+	}
+
+	if description.Deprecated {
+		_, _ = fmt.Fprintf(out, "\n/**\n * Calls the %s endpoints.\n * @deprecated Version %s of the API is deprecated.\n */\nexport class %sResource {\n", path, description.Version, resourceName)
+	} else {
+		_, _ = fmt.Fprintf(out, "\n/** Calls the %s endpoints. */\nexport class %sResource {\n", path, resourceName)
+	}
+	_, _ = fmt.Fprintf(out, "\tconstructor(private readonly client: SasClient, private readonly path = %q) {}\n", path)
+
+	for _, route := range description.Routes {
+		switch {
+		case route.Operation == sas.OperationList:
+			_, _ = fmt.Fprintf(out, "\n\tlist(options?: ListOptions): Promise<%s[]> {\n\t\treturn this.client.request(\"GET\", this.path, { query: listQuery(options) });\n\t}\n", modelName)
+		case route.Operation == sas.OperationGet:
+			_, _ = fmt.Fprintf(out, "\n\tget(id: number): Promise<%s> {\n\t\treturn this.client.request(\"GET\", `${this.path}/${id}`);\n\t}\n", modelName)
+		case route.Operation == sas.OperationCreate:
+			_, _ = fmt.Fprintf(out, "\n\tcreate(body: %s): Promise<void> {\n\t\treturn this.client.request(\"POST\", this.path, { body });\n\t}\n", createType)
+		case route.Operation == sas.OperationWrite && route.Method == http.MethodPut:
+			_, _ = fmt.Fprintf(out, "\n\tupdate(id: number, body: %s): Promise<void> {\n\t\treturn this.client.request(\"PUT\", `${this.path}/${id}`, { body });\n\t}\n", writeType)
+		case route.Operation == sas.OperationDelete:
+			_, _ = fmt.Fprintf(out, "\n\tdelete(id: number): Promise<void> {\n\t\treturn this.client.request(\"DELETE\", `${this.path}/${id}`);\n\t}\n")
+		case route.Operation == sas.OperationBulkCreate:
+			g.useBulk = true
+			_, _ = fmt.Fprintf(out, "\n\tbulkCreate(items: %s[], mode?: BulkMode): Promise<BulkResponse> {\n\t\treturn this.client.request(\"POST\", `${this.path}/bulk`, { query: bulkQuery(mode), body: items });\n\t}\n", createType)
+		case route.Operation == sas.OperationBulkWrite:
+			g.useBulk = true
+			_, _ = fmt.Fprintf(out, "\n\tbulkUpdate(items: { id: number; changes: Partial<%s> }[], mode?: BulkMode): Promise<BulkResponse> {\n\t\treturn this.client.request(\"PATCH\", `${this.path}/bulk`, { query: bulkQuery(mode), body: items });\n\t}\n", writeType)
+		case route.Operation == sas.OperationBulkDelete:
+			g.useBulk = true
+			_, _ = fmt.Fprintf(out, "\n\tbulkDelete(ids: number[], mode?: BulkMode): Promise<BulkResponse> {\n\t\treturn this.client.request(\"DELETE\", this.path, { query: { ...bulkQuery(mode), ids: ids.join(\",\") } });\n\t}\n")
+		case route.Operation == sas.OperationHistory:
+			g.useHistory = true
+			_, _ = fmt.Fprintf(out, "\n\thistory(id: number, options?: ListOptions): Promise<HistoryEntry<%s>[]> {\n\t\treturn this.client.request(\"GET\", `${this.path}/${id}/history`, { query: listQuery(options) });\n\t}\n", modelName)
+		case route.Operation == sas.OperationHistoryVersion:
+			_, _ = fmt.Fprintf(out, "\n\thistoryVersion(id: number, version: number): Promise<%s> {\n\t\treturn this.client.request(\"GET\", `${this.path}/${id}/history/${version}`);\n\t}\n", modelName)
+		case route.Operation == sas.OperationRevert:
+			_, _ = fmt.Fprintf(out, "\n\trevert(id: number, version: number): Promise<void> {\n\t\treturn this.client.request(\"POST\", `${this.path}/${id}/revert/${version}`);\n\t}\n")
+		case route.Operation == sas.OperationAction:
+			imports = append(imports, g.writeAction(out, resourceName, route)...)
+		}
+	}
+
+	out.WriteString("}\n")
+
+	var typeImports []string
+	for _, name := range imports {
+		if g.used[name] {
+			typeImports = append(typeImports, name)
+		}
+	}
+
+	return typeImports
+}
+
+// writeAction writes the method of a custom action, and returns the type names it uses.
+func (g *generator) writeAction(out *strings.Builder, resourceName string, route sas.RouteDescription) []string {
+	method := pascalCase(route.Action)
+	method = strings.ToLower(method[:1]) + method[1:]
+
+	requestType := "unknown"
+	var imports []string
+	if route.RequestType != nil {
+		requestType = g.named(route.RequestType, resourceName+pascalCase(route.Action)+"Request")
+		imports = append(imports, requestType)
+	}
+
+	if strings.Contains(route.Path, ":") {
+		_, _ = fmt.Fprintf(out, "\n\t%s(id: number, body?: %s): Promise<unknown> {\n\t\treturn this.client.request(%q, `${this.path}/${id}/%s`, { body: body ?? {} });\n\t}\n",
+			method, requestType, route.Method, route.Action)
+	} else {
+		_, _ = fmt.Fprintf(out, "\n\t%s(body?: %s): Promise<unknown> {\n\t\treturn this.client.request(%q, `${this.path}/%s`, { body: body ?? {} });\n\t}\n",
+			method, requestType, route.Method, route.Action)
+	}
+
+	return imports
+}
+
+// named returns the name of a declared type for t. Anonymous structs are declared under fallback.
+func (g *generator) named(t reflect.Type, fallback string) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	if t.Kind() != reflect.Struct || t == timeType || (t.Name() == "" && fallback == "") {
+		return g.typeOf(t)
+	}
+
+	name := t.Name()
+	if name == "" {
+		name = fallback
+	}
+	name = g.reserve(pascalCase(name))
+	g.names[t] = name
+
+	// The declaration is added after its fields, so referenced types come first.
+	g.declarations = append(g.declarations, g.interfaceOf(t, name))
+
+	return name
+}
+
+func (g *generator) reserve(name string) string {
+	if name == "" || name == "DateTime" {
+		name = "Model" + name
+	}
+
+	candidate := name
+	for i := 2; g.used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	g.used[candidate] = true
+
+	return candidate
+}
+
+func (g *generator) interfaceOf(t reflect.Type, name string) string {
+	var out strings.Builder
+	_, _ = fmt.Fprintf(&out, "export interface %s %s\n", name, g.objectOf(t, ""))
+
+	return out.String()
+}
+
+func (g *generator) objectOf(t reflect.Type, indent string) string {
+	var out strings.Builder
+	out.WriteString("{\n")
+
+	for _, field := range openapi.JSONFields(t) {
+		optional := ""
+		if field.OmitEmpty && !field.Required {
+			optional = "?"
+		}
+
+		key := field.Name
+		if !identifierPattern.MatchString(key) {
+			key = fmt.Sprintf("%q", key)
+		}
+
+		fieldType := g.typeOfIndented(field.Type, indent+"\t")
+		_, _ = fmt.Fprintf(&out, "%s\t%s%s: %s;\n", indent, key, optional, fieldType)
+	}
+
+	out.WriteString(indent + "}")
+	return out.String()
+}
+
+func (g *generator) typeOf(t reflect.Type) string {
+	return g.typeOfIndented(t, "")
+}
+
+func (g *generator) typeOfIndented(t reflect.Type, indent string) string {
+	if t.Kind() == reflect.Pointer {
+		return g.typeOfIndented(t.Elem(), indent) + " | null"
+	}
+
+	switch {
+	case t == timeType:
+		return "DateTime"
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return "unknown"
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return "string"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json encodes byte slices as base64 strings.
+			return "string"
+		}
+		element := g.typeOfIndented(t.Elem(), indent)
+		if strings.Contains(element, " ") {
+			element = "(" + element + ")"
+		}
+		return element + "[]"
+	case reflect.Map:
+		return "Record<string, " + g.typeOfIndented(t.Elem(), indent) + ">"
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.objectOf(t, indent)
+		}
+		return g.named(t, "")
+	}
+
+	return "unknown"
+}
+
+func pascalCase(name string) string {
+	var out strings.Builder
+	for _, part := range nonAlphanumeric.Split(name, -1) {
+		if part == "" {
+			continue
+		}
+		out.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+
+	return out.String()
+}
+
+func uniqueSorted(values []string) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+const clientRuntime = `// imports
+
+/** Narrows down the entities returned by list. */
+export interface ListOptions {
+	limit?: number;
+	offset?: number;
+	query?: Record<string, string>;
+}
+
+/** Thrown for any response which is not successful, with the message written by the server. */
+export class SasError extends Error {
+	constructor(readonly status: number, message: string) {
+		super(message);
+		this.name = "SasError";
+	}
+}
+
+export interface SasClientOptions {
+	headers?: Record<string, string>;
+	fetch?: typeof fetch;
+}
+
+/** Holds the connection settings shared by every resource. */
+export class SasClient {
+	constructor(private readonly baseUrl: string, private readonly options: SasClientOptions = {}) {}
+
+	async request<T>(method: string, path: string, init: { query?: Record<string, string>; body?: unknown } = {}): Promise<T> {
+		let url = this.baseUrl.replace(/\/+$/, "") + path;
+		const query = new URLSearchParams(init.query ?? {}).toString();
+		if (query) {
+			url += "?" + query;
+		}
+
+		const headers: Record<string, string> = { Accept: "application/json", ...this.options.headers };
+		if (init.body !== undefined) {
+			headers["Content-Type"] = "application/json";
+		}
+
+		const doFetch = this.options.fetch ?? fetch;
+		const response = await doFetch(url, {
+			method,
+			headers,
+			body: init.body === undefined ? undefined : JSON.stringify(init.body),
+		});
+
+		if (!response.ok) {
+			throw new SasError(response.status, (await response.text()).trim());
+		}
+
+		const text = await response.text();
+		return (text ? JSON.parse(text) : undefined) as T;
+	}
+}
+
+function listQuery(options?: ListOptions): Record<string, string> {
+	const query: Record<string, string> = { ...options?.query };
+	if (options?.limit) {
+		query.limit = String(options.limit);
+	}
+	if (options?.offset) {
+		query.offset = String(options.offset);
+	}
+	return query;
+}
+`
+
+const bulkRuntime = `
+/** atomic applies every item or none of them, partial applies the items which succeed. */
+export type BulkMode = "atomic" | "partial";
+
+/** The result of a single item of a bulk request. */
+export interface BulkItemResult {
+	index: number;
+	id?: number;
+	status: number;
+	error?: string;
+}
+
+/** Reports the result of each item of a bulk request, in the order of the request. */
+export interface BulkResponse {
+	mode: BulkMode;
+	rolledBack: boolean;
+	results: BulkItemResult[];
+}
+
+function bulkQuery(mode?: BulkMode): Record<string, string> {
+	return mode ? { mode } : {};
+}
+`
+
+const historyRuntime = `
+/** A prior version of an entity, and the operation which replaced it. */
+export interface HistoryEntry<T> {
+	entityId: number;
+	version: number;
+	operation: string;
+	data: T;
+	timestamp: string;
+}
+`
@@ -0,0 +1,78 @@
+package tsgen
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/imthatgin/sas/pkg/endpoints"
+	"github.com/imthatgin/sas/pkg/sas"
+	"github.com/stretchr/testify/assert"
+)
+
+type author struct {
+	Name string `json:"name"`
+}
+
+type post struct {
+	sas.DefaultModel
+
+	Title    string            `json:"title"`
+	Subtitle *string           `json:"subtitle,omitempty"`
+	Tags     []string          `json:"tags"`
+	Meta     map[string]int    `json:"meta"`
+	Author   author            `json:"author"`
+	Hidden   string            `json:"-"`
+	Raw      []byte            `json:"raw"`
+	Extra    map[string]author `json:"extra,omitempty"`
+}
+
+type postCreate struct {
+	Title string `json:"title"`
+}
+
+func TestRender(t *testing.T) {
+	posts := sas.FromModel[post]("blog-posts", nil, sas.NewPolicy[post](endpoints.GET|endpoints.POST|endpoints.PUT))
+	posts.CreateBindType(postCreate{})
+	posts.WriteBindType(struct {
+		Title string `json:"title,omitempty"`
+	}{})
+
+	files := Render([]sas.Provider{&posts})
+	types := files[TypesFile]
+	client := files[ClientFile]
+
+	assert.Contains(t, types, "export interface Post {\n\tID: number;\n\tCreated: DateTime;\n\tUpdated: DateTime;\n\ttitle: string;\n")
+	assert.Contains(t, types, "\tsubtitle?: string | null;\n")
+	assert.Contains(t, types, "\ttags: string[];\n")
+	assert.Contains(t, types, "\tmeta: Record<string, number>;\n")
+	assert.Contains(t, types, "\tauthor: Author;\n")
+	assert.Contains(t, types, "\traw: string;\n")
+	assert.Contains(t, types, "\textra?: Record<string, Author>;\n")
+	assert.NotContains(t, types, "Hidden")
+	assert.Contains(t, types, "export interface Author {\n\tname: string;\n}")
+	assert.Contains(t, types, "export interface PostCreate {\n\ttitle: string;\n}")
+	assert.Contains(t, types, "export interface BlogPostsWrite {\n\ttitle?: string;\n}")
+
+	assert.Contains(t, client, `import type { BlogPostsWrite, Post, PostCreate } from "./types";`)
+	assert.Contains(t, client, "export class BlogPostsResource {")
+	assert.Contains(t, client, "list(options?: ListOptions): Promise<Post[]>")
+	assert.Contains(t, client, "get(id: number): Promise<Post>")
+	assert.Contains(t, client, "create(body: PostCreate): Promise<void>")
+	assert.Contains(t, client, "update(id: number, body: BlogPostsWrite): Promise<void>")
+	assert.NotContains(t, client, "delete(")
+}
+
+func TestCommand(t *testing.T) {
+	posts := sas.FromModel[post]("posts", nil, sas.NewPolicy[post](endpoints.AllEndpoints))
+	dir := filepath.Join(t.TempDir(), "client")
+
+	var out bytes.Buffer
+	assert.NoError(t, Command([]sas.Provider{&posts}, []string{"tsgen", "-out", dir}, &out))
+
+	for _, name := range []string{TypesFile, ClientFile, IndexFile} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		assert.NoError(t, err)
+	}
+}